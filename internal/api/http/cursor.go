@@ -0,0 +1,170 @@
+package http
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+var (
+	errInvalidCursor = errors.New("invalid cursor")
+)
+
+// encodeCursor makes opaque representation of the cursor, nil cursor is encoded to empty string.
+func encodeCursor(c *model.NotificationCursor) string {
+	if c == nil {
+		return ""
+	}
+
+	raw := string(c.Priority) + ":" + strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + ":" + strconv.Itoa(c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (*model.NotificationCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), ":", 3)
+	if len(parts) != 3 {
+		return nil, errInvalidCursor
+	}
+
+	p := model.Priority(parts[0])
+	if !p.Valid() {
+		return nil, errInvalidCursor
+	}
+
+	ns, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	id, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	return &model.NotificationCursor{Priority: p, CreatedAt: time.Unix(0, ns), ID: id}, nil
+}
+
+// parseInboxFilter reads inbox filter from query parameters:
+// cursor, limit, type and priority (may be repeated), from, till (RFC3339) and read (true/false).
+func parseInboxFilter(r *http.Request) (*model.InboxFilter, error) {
+	q := r.URL.Query()
+
+	filter := &model.InboxFilter{
+		Types: q["type"],
+	}
+
+	for _, p := range q["priority"] {
+		filter.Priorities = append(filter.Priorities, model.Priority(p))
+	}
+
+	if c := q.Get("cursor"); c != "" {
+		cursor, err := decodeCursor(c)
+		if err != nil {
+			return nil, err
+		}
+		filter.After = cursor
+	}
+
+	if l := q.Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing limit")
+		}
+		filter.Limit = limit
+	}
+
+	if f := q.Get("from"); f != "" {
+		from, err := time.Parse(time.RFC3339, f)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing from")
+		}
+		filter.CreatedFrom = &from
+	}
+
+	if t := q.Get("till"); t != "" {
+		till, err := time.Parse(time.RFC3339, t)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing till")
+		}
+		filter.CreatedTill = &till
+	}
+
+	if rd := q.Get("read"); rd != "" {
+		read, err := strconv.ParseBool(rd)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing read")
+		}
+		filter.Read = &read
+	}
+
+	return filter, nil
+}
+
+// encodeStreamPosition makes opaque event id of notification in stream.
+func encodeStreamPosition(n *model.Notification) string {
+	if n.PublishSeq == nil {
+		return ""
+	}
+
+	raw := strconv.FormatInt(*n.PublishSeq, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeStreamPosition(s string) (*model.StreamPosition, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	seq, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	return &model.StreamPosition{PublishSeq: seq}, nil
+}
+
+// encodeSearchCursor makes opaque representation of the search cursor, nil cursor is encoded to empty string.
+func encodeSearchCursor(c *model.SearchCursor) string {
+	if c == nil {
+		return ""
+	}
+
+	raw := strconv.FormatFloat(c.Rank, 'g', -1, 64) + ":" + strconv.Itoa(c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeSearchCursor(s string) (*model.SearchCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return nil, errInvalidCursor
+	}
+
+	rank, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	return &model.SearchCursor{Rank: rank, ID: id}, nil
+}
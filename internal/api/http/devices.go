@@ -0,0 +1,70 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+type registerDeviceRequest struct {
+	Platform string `json:"platform" validate:"required,oneof=fcm apns"`
+	Token    string `json:"token" validate:"required"`
+}
+
+func (srv *Server) registerDevice(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	request := new(registerDeviceRequest)
+
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	device := &model.Device{
+		Platform: request.Platform,
+		Token:    request.Token,
+	}
+
+	err := srv.app.RegisterDevice(ctx, sessionUser(ctx), device)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: device})
+}
+
+func (srv *Server) getDevices(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	devices, err := srv.app.ListDevices(ctx, sessionUser(ctx))
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: devices})
+}
+
+func (srv *Server) unregisterDevice(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	err = srv.app.UnregisterDevice(ctx, sessionUser(ctx), id)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondRaw(ctx, w, http.StatusNoContent)
+}
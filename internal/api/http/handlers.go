@@ -1,11 +1,19 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/go-chi/chi"
+	imiddleware "github.com/hummerd/gophercon/internal/api/http/middleware"
 	"github.com/hummerd/gophercon/internal/config"
+	"github.com/hummerd/gophercon/internal/controller"
+	"github.com/hummerd/gophercon/internal/dataprovider"
 	"github.com/hummerd/gophercon/internal/model"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
 
@@ -51,11 +59,25 @@ func (srv *Server) setLogLevel(w http.ResponseWriter, r *http.Request) {
 	respondOK(ctx, w, logLevel{Level: request.Level})
 }
 
+// createNotificationRequest holds either notification content
+// or template key with variables to render content from.
 type createNotificationRequest struct {
-	UserID *int64 `json:"user_id"`
-	Title  string `json:"title" validate:"required"`
-	Body   string `json:"body" validate:"required"`
-	Type   string `json:"type" validate:"required"`
+	UserID    *int64            `json:"user_id"`
+	Title     string            `json:"title" validate:"required_without=Template"`
+	Body      string            `json:"body" validate:"required_without=Template"`
+	Type      string            `json:"type" validate:"required_without=Template"`
+	Priority  model.Priority    `json:"priority" validate:"omitempty,oneof=low normal high critical"`
+	Template  string            `json:"template"`
+	Variables map[string]string `json:"variables"`
+	FromTime  *time.Time        `json:"from_time"`
+	TillTime  *time.Time        `json:"till_time" validate:"omitempty,gtfield=FromTime"`
+	PublishAt *time.Time        `json:"publish_at"`
+	// CollapseKey merges notification into active notification with the same key and recipient.
+	CollapseKey string `json:"collapse_key" validate:"omitempty,max=255"`
+	// Language is a text search configuration of notification, configured language is used by default.
+	Language string `json:"language"`
+
+	Translations map[string]model.NotificationContent `json:"translations"`
 }
 
 func (srv *Server) createNotification(w http.ResponseWriter, r *http.Request) {
@@ -68,18 +90,340 @@ func (srv *Server) createNotification(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	notification, err := srv.requestedNotification(ctx, request)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	err = srv.app.CreateNotification(ctx, notification)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: notification})
+}
+
+// requestedNotification makes notification described by request, rendering its template if one is set.
+func (srv *Server) requestedNotification(ctx context.Context, request *createNotificationRequest) (*model.Notification, error) {
 	notification := &model.Notification{
-		UserID: request.UserID,
-		Title:  request.Title,
-		Type:   request.Type,
-		Body:   request.Body,
+		Title: request.Title,
+		Type:  request.Type,
+		Body:  request.Body,
+	}
+
+	if request.Template != "" {
+		var err error
+		notification, err = srv.app.RenderNotification(ctx, request.Template, request.Variables)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	notification.UserID = request.UserID
+	notification.Priority = request.Priority
+	notification.FromTime = request.FromTime
+	notification.TillTime = request.TillTime
+	notification.PublishAt = request.PublishAt
+	notification.CollapseKey = request.CollapseKey
+	notification.Language = request.Language
+	notification.Translations = request.Translations
+
+	return notification, nil
+}
+
+// bulkNotificationRequest creates either the same notification for every user from UserIDs
+// or every notification from Notifications.
+type bulkNotificationRequest struct {
+	UserIDs       []int64                      `json:"user_ids"`
+	Title         string                       `json:"title"`
+	Body          string                       `json:"body"`
+	Type          string                       `json:"type"`
+	Priority      model.Priority               `json:"priority"`
+	FromTime      *time.Time                   `json:"from_time"`
+	TillTime      *time.Time                   `json:"till_time"`
+	PublishAt     *time.Time                   `json:"publish_at"`
+	CollapseKey   string                       `json:"collapse_key"`
+	Language      string                       `json:"language"`
+	Notifications []*createNotificationRequest `json:"notifications"`
+}
+
+type bulkNotificationResult struct {
+	ID    int    `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+func (srv *Server) createNotificationsBulk(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	request := new(bulkNotificationRequest)
+
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	results := make([]bulkNotificationResult, len(request.UserIDs)+len(request.Notifications))
+	notifications := make([]*model.Notification, 0, len(results))
+	// positions are indexes of results of notifications
+	positions := make([]int, 0, len(results))
+
+	for i := range request.UserIDs {
+		notifications = append(notifications, &model.Notification{
+			UserID:    &request.UserIDs[i],
+			Title:     request.Title,
+			Type:      request.Type,
+			Priority:  request.Priority,
+			Body:      request.Body,
+			FromTime:  request.FromTime,
+			TillTime:  request.TillTime,
+			PublishAt: request.PublishAt,
+
+			CollapseKey: request.CollapseKey,
+			Language:    request.Language,
+		})
+		positions = append(positions, i)
+	}
+
+	// Item which template can't be rendered gets its error and is not created
+	for i, item := range request.Notifications {
+		pos := len(request.UserIDs) + i
+
+		n, err := srv.requestedNotification(ctx, item)
+		switch errors.Cause(err) {
+		case nil:
+			notifications = append(notifications, n)
+			positions = append(positions, pos)
+		case dataprovider.ErrNotFound, controller.ErrInvalidTemplate, controller.ErrMissingVariable:
+			results[pos].Error = err.Error()
+		default:
+			respondError(ctx, w, err)
+			return
+		}
+	}
+
+	errs, err := srv.app.CreateNotifications(ctx, notifications)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	for i, n := range notifications {
+		pos := positions[i]
+		if errs[i] != nil {
+			results[pos].Error = errs[i].Error()
+			continue
+		}
+		results[pos].ID = n.ID
 	}
 
-	err := srv.app.CreateNotification(ctx, notification)
+	respondOK(ctx, w, data{Data: results})
+}
+
+type updateNotificationRequest struct {
+	Title    *string         `json:"title"`
+	Body     *string         `json:"body"`
+	Type     *string         `json:"type"`
+	Priority *model.Priority `json:"priority"`
+	FromTime nullableTime    `json:"from_time"`
+	TillTime nullableTime    `json:"till_time"`
+	Version  int             `json:"version" validate:"required"`
+}
+
+// nullableTime tells null from absent field, null clears time while absent field leaves it unchanged.
+type nullableTime model.TimePatch
+
+func (t *nullableTime) UnmarshalJSON(b []byte) error {
+	t.Set = true
+	return json.Unmarshal(b, &t.Time)
+}
+
+func (srv *Server) updateNotification(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
 		respondError(ctx, w, err)
 		return
 	}
 
-	respondOK(ctx, w, data{notification})
+	request := new(updateNotificationRequest)
+
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	patch := &model.NotificationPatch{
+		Title:    request.Title,
+		Body:     request.Body,
+		Type:     request.Type,
+		Priority: request.Priority,
+		FromTime: model.TimePatch(request.FromTime),
+		TillTime: model.TimePatch(request.TillTime),
+		Version:  request.Version,
+	}
+
+	notification, err := srv.app.UpdateNotification(ctx, id, patch)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: notification})
+}
+
+func (srv *Server) deleteNotification(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	err = srv.app.RevokeNotification(ctx, id)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondRaw(ctx, w, http.StatusNoContent)
+}
+
+func (srv *Server) getNotifications(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	filter, err := parseInboxFilter(r)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	user := sessionUser(ctx)
+	user.Locales = preferredLocales(r)
+
+	notifications, next, err := srv.app.GetUserNotifications(ctx, user, filter)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: notifications, NextCursor: encodeCursor(next)})
+}
+
+func (srv *Server) getAdminNotifications(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	q := r.URL.Query()
+
+	filter := &model.NotificationFilter{
+		State: q.Get("state"),
+	}
+
+	if uid := q.Get("user_id"); uid != "" {
+		id, err := strconv.ParseInt(uid, 10, 64)
+		if err != nil {
+			respondError(ctx, w, err)
+			return
+		}
+		filter.UserID = &id
+	}
+
+	if c := q.Get("cursor"); c != "" {
+		cursor, err := decodeCursor(c)
+		if err != nil {
+			respondError(ctx, w, err)
+			return
+		}
+		filter.After = cursor
+	}
+
+	if l := q.Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil {
+			respondError(ctx, w, err)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	notifications, next, err := srv.app.ListNotifications(ctx, filter)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: notifications, NextCursor: encodeCursor(next)})
+}
+
+// getNotificationDeliveries returns statuses of notification's deliveries through channels.
+func (srv *Server) getNotificationDeliveries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	deliveries, err := srv.app.ListNotificationDeliveries(ctx, id)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: deliveries})
+}
+
+func (srv *Server) markRead(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	err = srv.app.MarkRead(ctx, sessionUser(ctx), id)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondRaw(ctx, w, http.StatusNoContent)
+}
+
+func (srv *Server) markAllRead(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	err := srv.app.MarkAllRead(ctx, sessionUser(ctx))
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondRaw(ctx, w, http.StatusNoContent)
+}
+
+type unreadCountResponse struct {
+	Count int `json:"count"`
+}
+
+func (srv *Server) getUnreadCount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	count, err := srv.app.CountUnread(ctx, sessionUser(ctx))
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: unreadCountResponse{Count: count}})
+}
+
+// sessionUser returns user of the authenticated request.
+func sessionUser(ctx context.Context) *model.User {
+	return &model.User{ID: imiddleware.GetSession(ctx).UserID}
 }
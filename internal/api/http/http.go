@@ -7,6 +7,9 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
+
+	"github.com/hummerd/gophercon/internal/controller"
+	"github.com/hummerd/gophercon/internal/dataprovider"
 )
 
 const (
@@ -17,7 +20,8 @@ const (
 )
 
 type data struct {
-	Data interface{} `json:"data"`
+	Data       interface{} `json:"data"`
+	NextCursor string      `json:"next_cursor,omitempty"`
 }
 
 type errResp struct {
@@ -26,7 +30,22 @@ type errResp struct {
 
 func respondError(ctx context.Context, w http.ResponseWriter, err error) {
 	errCause := errors.Cause(err)
-	respondJSON(ctx, w, http.StatusBadRequest, errResp{errCause})
+
+	switch errCause {
+	case dataprovider.ErrNotFound:
+		respondNotFound(ctx, w)
+	case dataprovider.ErrVersionConflict, dataprovider.ErrAlreadyExists:
+		respondRaw(ctx, w, http.StatusConflict)
+	case controller.ErrIdempotencyKeyInFlight:
+		w.Header().Set("Retry-After", "1")
+		respondJSON(ctx, w, http.StatusConflict, errResp{errCause.Error()})
+	case controller.ErrIdempotencyKeyReused:
+		respondJSON(ctx, w, http.StatusConflict, errResp{errCause.Error()})
+	case controller.ErrMandatoryType:
+		respondJSON(ctx, w, http.StatusForbidden, errResp{errCause.Error()})
+	default:
+		respondJSON(ctx, w, http.StatusBadRequest, errResp{errCause})
+	}
 }
 
 func respondJSON(ctx context.Context, w http.ResponseWriter, code int, data interface{}) {
@@ -0,0 +1,135 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/rs/zerolog"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+const (
+	headerIdempotencyKey     = "Idempotency-Key"
+	headerIdempotentReplayed = "Idempotent-Replayed"
+)
+
+// idempotent makes requests with Idempotency-Key header processed once per user and key.
+// Response of processed request is replayed for repeated requests with the same body,
+// reusing key for different request is rejected with 409. Only successful responses and
+// known client errors are stored, other requests may be retried with the same key.
+func (srv *Server) idempotent(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(headerIdempotencyKey)
+		if key == "" {
+			h(w, r)
+			return
+		}
+
+		ctx := r.Context()
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			respondError(ctx, w, err)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+		hash.Write(body)
+
+		stored := &model.IdempotencyKey{
+			UserID:      sessionUser(ctx).ID,
+			Key:         key,
+			RequestHash: hex.EncodeToString(hash.Sum(nil)),
+		}
+
+		replay, err := srv.app.BeginIdempotent(ctx, stored.UserID, stored.Key, stored.RequestHash)
+		if err != nil {
+			respondError(ctx, w, err)
+			return
+		}
+
+		if replay != nil {
+			if replay.ContentType != "" {
+				w.Header().Set(headerContentType, replay.ContentType)
+			}
+			w.Header().Set(headerIdempotentReplayed, "true")
+			w.WriteHeader(replay.StatusCode)
+			w.Write(replay.Response)
+			return
+		}
+
+		sw := &snapshotWriter{ResponseWriter: w, code: http.StatusOK}
+		completed := false
+
+		defer func() {
+			if completed {
+				return
+			}
+
+			// Request is not finished (e.g. handler panicked), context of request may be canceled already
+			err := srv.app.ReleaseIdempotent(context.Background(), stored.UserID, stored.Key)
+			if err != nil {
+				zerolog.Ctx(ctx).Error().Err(err).Msg("can not release idempotency key")
+			}
+		}()
+
+		h(sw, r)
+
+		if !storableResponse(sw.code) {
+			return
+		}
+
+		stored.StatusCode = sw.code
+		stored.ContentType = sw.Header().Get(headerContentType)
+		stored.Response = sw.body.Bytes()
+
+		completed = true
+
+		err = srv.app.CompleteIdempotent(context.Background(), stored)
+		if err != nil {
+			// Key is held until its lease expires, so request is not processed twice meanwhile
+			zerolog.Ctx(ctx).Error().Err(err).Msg("can not store response of idempotent request")
+		}
+	}
+}
+
+// storableResponse reports whether response with code is final for idempotent request.
+// respondError reports unknown errors (e.g. failed database) as bad request,
+// so bad request is not stored as it may be transient.
+func storableResponse(code int) bool {
+	switch code {
+	case http.StatusNotFound, http.StatusConflict, http.StatusForbidden:
+		return true
+	}
+
+	return code >= http.StatusOK && code < http.StatusMultipleChoices
+}
+
+// snapshotWriter keeps copy of response status and body
+type snapshotWriter struct {
+	http.ResponseWriter
+	code        int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (w *snapshotWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.code = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *snapshotWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	imiddleware "github.com/hummerd/gophercon/internal/api/http/middleware"
+)
+
+const (
+	headerAcceptLanguage = "Accept-Language"
+)
+
+// preferredLocales returns caller's locales in order of preference:
+// locale from session goes first, then locales from Accept-Language header ordered by quality.
+func preferredLocales(r *http.Request) []string {
+	locales := make([]string, 0, 4)
+
+	if s := imiddleware.GetSession(r.Context()); s != nil && s.Locale != "" {
+		locales = append(locales, s.Locale)
+	}
+
+	return append(locales, parseAcceptLanguage(r.Header.Get(headerAcceptLanguage))...)
+}
+
+// parseAcceptLanguage parses header value like "ru-RU,ru;q=0.9,en;q=0.8",
+// wildcard and locales with zero quality are skipped.
+func parseAcceptLanguage(h string) []string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+
+	parsed := make([]weighted, 0, 4)
+
+	for _, part := range strings.Split(h, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		w := weighted{locale: part, q: 1}
+
+		if i := strings.IndexByte(part, ';'); i >= 0 {
+			w.locale = strings.TrimSpace(part[:i])
+
+			param := strings.TrimSpace(part[i+1:])
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil {
+					continue
+				}
+				w.q = q
+			}
+		}
+
+		if w.locale == "*" || w.locale == "" || w.q <= 0 {
+			continue
+		}
+
+		parsed = append(parsed, w)
+	}
+
+	sort.SliceStable(parsed, func(i, j int) bool {
+		return parsed[i].q > parsed[j].q
+	})
+
+	locales := make([]string, len(parsed))
+	for i := range parsed {
+		locales[i] = parsed[i].locale
+	}
+
+	return locales
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/hummerd/gophercon/internal/model"
+	"github.com/hummerd/gophercon/internal/service"
+)
+
+const (
+	// SessionCookie is a name of the cookie that holds session token.
+	SessionCookie = "session"
+
+	bearerPrefix = "Bearer "
+)
+
+type sessionContextKey int
+
+var (
+	sessionKey sessionContextKey = 1
+)
+
+// GetSession returns session stored in context by Auth middleware,
+// nil is returned for anonymous requests.
+func GetSession(ctx context.Context) *model.Session {
+	s, _ := ctx.Value(sessionKey).(*model.Session)
+	return s
+}
+
+// WithSession returns copy of ctx that holds session.
+func WithSession(ctx context.Context, s *model.Session) context.Context {
+	return context.WithValue(ctx, sessionKey, s)
+}
+
+// Auth resolves session token from "Authorization: Bearer" header or session cookie
+// and stores session in request's context.
+// Requests with unknown token are rejected with 401, if session-store is unavailable 503 is returned.
+// Requests without token are rejected only when session is required.
+func Auth(store service.SessionStore, required bool) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := sessionToken(r)
+			if token == "" {
+				if required {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+
+			session, err := store.GetSessionByToken(ctx, token)
+			if err != nil {
+				zerolog.Ctx(ctx).Error().
+					Err(err).
+					Msg("can not resolve session")
+
+				if errors.Cause(err) == service.ErrSessionStoreUnavailble {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				} else {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+				return
+			}
+			if session == nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			r = r.WithContext(WithSession(ctx, session))
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireAdmin rejects requests which session has no administrative rights with 403.
+// It must be used after Auth middleware.
+func RequireAdmin() func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s := GetSession(r.Context())
+			if s == nil || !s.IsAdmin {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+func sessionToken(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if len(h) > len(bearerPrefix) && strings.EqualFold(h[:len(bearerPrefix)], bearerPrefix) {
+		return strings.TrimSpace(h[len(bearerPrefix):])
+	}
+
+	c, err := r.Cookie(SessionCookie)
+	if err == nil {
+		return c.Value
+	}
+
+	return ""
+}
@@ -1,7 +1,9 @@
 package middleware
 
 import (
+	"bufio"
 	"io"
+	"net"
 	"net/http"
 	"strings"
 	"sync"
@@ -9,6 +11,8 @@ import (
 
 	"github.com/hummerd/gostuff/ioutil"
 	"github.com/rs/zerolog"
+
+	"github.com/hummerd/gophercon/internal/websocket"
 )
 
 // middlewareTrace tracing all http requests
@@ -45,6 +49,24 @@ func Log(logger *zerolog.Logger, excludePath ...string) func(http.Handler) http.
 			lc := l.WithContext(r.Context())
 			r = r.WithContext(lc)
 
+			// Long-lived streams are neither buffered nor logged with body,
+			// only stream's start and end are logged
+			if IsStream(r) {
+				l.Debug().
+					Str("url", url).
+					Str("method", r.Method).
+					Msg("stream started")
+
+				h.ServeHTTP(w, r)
+
+				l.Debug().
+					Str("url", url).
+					Str("method", r.Method).
+					Dur("duration", time.Since(start)).
+					Msg("stream finished")
+				return
+			}
+
 			if l.Debug() != nil {
 				// Use ioutil.PrefixReader to log request's body
 				cr := rpool.Get().(*ioutil.PrefixReader)
@@ -93,6 +115,13 @@ func Log(logger *zerolog.Logger, excludePath ...string) func(http.Handler) http.
 	}
 }
 
+// IsStream reports whether request asks for long-lived streaming response
+// (Server-Sent Events or WebSocket).
+func IsStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream") ||
+		websocket.IsUpgrade(r)
+}
+
 func newCachedWriter(w http.ResponseWriter, s int) *cachedWriter {
 	return &cachedWriter{
 		PrefixWriter: *ioutil.NewPrefixWriter(w, s),
@@ -141,3 +170,17 @@ func (cw *cachedWriter) Flush() {
 		f.Flush()
 	}
 }
+
+// Unwrap returns underlying writer, it allows http.ResponseController to reach original writer.
+func (cw *cachedWriter) Unwrap() http.ResponseWriter {
+	return cw.w
+}
+
+// Hijack lets handlers take over connection, e.g. to upgrade it to WebSocket.
+func (cw *cachedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := cw.w.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return h.Hijack()
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+// getOutbox returns outbox entries from the latest, entries are filtered by status query parameter
+// and paginated by before (id of the last entry of previous page) and limit query parameters.
+func (srv *Server) getOutbox(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	q := r.URL.Query()
+
+	filter := &model.OutboxFilter{
+		Status: q.Get("status"),
+	}
+
+	if before := q.Get("before"); before != "" {
+		id, err := strconv.ParseInt(before, 10, 64)
+		if err != nil {
+			respondError(ctx, w, err)
+			return
+		}
+		filter.BeforeID = id
+	}
+
+	filter.Limit, _ = strconv.Atoi(q.Get("limit"))
+
+	entries, err := srv.app.ListOutbox(ctx, filter)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: entries})
+}
+
+func (srv *Server) getOutboxEntry(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	entry, err := srv.app.GetOutboxEntry(ctx, id)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: entry})
+}
+
+func (srv *Server) replayOutboxEntry(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	err = srv.app.ReplayOutboxEntry(ctx, id)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondRaw(ctx, w, http.StatusAccepted)
+}
+
+type replayResponse struct {
+	Replayed int `json:"replayed"`
+}
+
+// replayFailedOutbox makes all failed outbox entries pending again.
+func (srv *Server) replayFailedOutbox(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	n, err := srv.app.ReplayFailedOutbox(ctx)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondJSON(ctx, w, http.StatusAccepted, data{Data: &replayResponse{Replayed: n}})
+}
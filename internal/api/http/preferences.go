@@ -0,0 +1,124 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+type preferencesRequest struct {
+	Preferences []*model.Preference `json:"preferences"`
+}
+
+// getPreferences returns user's preferences of notification types by channel along with mandatory types.
+func (srv *Server) getPreferences(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	preferences, err := srv.app.GetPreferences(ctx, sessionUser(ctx))
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: preferences})
+}
+
+// setPreferences replaces user's preferences, types without preference are enabled in all channels.
+func (srv *Server) setPreferences(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	request := new(preferencesRequest)
+
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	preferences, err := srv.app.SetPreferences(ctx, sessionUser(ctx), request.Preferences)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: preferences})
+}
+
+// setQuietHours sets user's quiet hours, non-critical email and push deliveries are deferred during them.
+func (srv *Server) setQuietHours(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	quietHours := new(model.QuietHours)
+
+	if err := json.NewDecoder(r.Body).Decode(quietHours); err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	preferences, err := srv.app.SetQuietHours(ctx, sessionUser(ctx), quietHours)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: preferences})
+}
+
+// deleteQuietHours turns user's quiet hours off.
+func (srv *Server) deleteQuietHours(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	err := srv.app.DeleteQuietHours(ctx, sessionUser(ctx))
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondRaw(ctx, w, http.StatusNoContent)
+}
+
+// setDigest sets user's digest, matching notifications are sent through digest channel as periodic summary.
+func (srv *Server) setDigest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	digest := new(model.Digest)
+
+	if err := json.NewDecoder(r.Body).Decode(digest); err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	preferences, err := srv.app.SetDigest(ctx, sessionUser(ctx), digest)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: preferences})
+}
+
+// deleteDigest turns user's digest off.
+func (srv *Server) deleteDigest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	err := srv.app.DeleteDigest(ctx, sessionUser(ctx))
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondRaw(ctx, w, http.StatusNoContent)
+}
+
+// previewDigest returns user's next digest rendered from notifications collected so far.
+func (srv *Server) previewDigest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	preview, err := srv.app.PreviewDigest(ctx, sessionUser(ctx))
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: preview})
+}
@@ -0,0 +1,211 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+type scheduleRequest struct {
+	Cron     string         `json:"cron" validate:"required"`
+	Timezone string         `json:"timezone"`
+	CatchUp  string         `json:"catch_up" validate:"omitempty,oneof=skip all"`
+	UserID   *int64         `json:"user_id"`
+	Type     string         `json:"type" validate:"required"`
+	Title    string         `json:"title" validate:"required"`
+	Body     string         `json:"body" validate:"required"`
+	Priority model.Priority `json:"priority"`
+}
+
+func (req *scheduleRequest) schedule() *model.Schedule {
+	return &model.Schedule{
+		Cron:     req.Cron,
+		Timezone: req.Timezone,
+		CatchUp:  req.CatchUp,
+		UserID:   req.UserID,
+		Type:     req.Type,
+		Title:    req.Title,
+		Body:     req.Body,
+		Priority: req.Priority,
+	}
+}
+
+func (srv *Server) createSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	request := new(scheduleRequest)
+
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	schedule := request.schedule()
+
+	err := srv.app.CreateSchedule(ctx, schedule)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: schedule})
+}
+
+func (srv *Server) getSchedules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	schedules, err := srv.app.ListSchedules(ctx)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: schedules})
+}
+
+func (srv *Server) getSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	schedule, err := srv.app.GetSchedule(ctx, id)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: schedule})
+}
+
+func (srv *Server) updateSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	request := new(scheduleRequest)
+
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	schedule := request.schedule()
+	schedule.ID = id
+
+	err = srv.app.UpdateSchedule(ctx, schedule)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: schedule})
+}
+
+func (srv *Server) deleteSchedule(w http.ResponseWriter, r *http.Request) {
+	srv.scheduleAction(w, r, srv.app.DeleteSchedule)
+}
+
+func (srv *Server) pauseSchedule(w http.ResponseWriter, r *http.Request) {
+	srv.scheduleAction(w, r, srv.app.PauseSchedule)
+}
+
+func (srv *Server) resumeSchedule(w http.ResponseWriter, r *http.Request) {
+	srv.scheduleAction(w, r, srv.app.ResumeSchedule)
+}
+
+func (srv *Server) scheduleAction(
+	w http.ResponseWriter,
+	r *http.Request,
+	action func(ctx context.Context, id int) error,
+) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	err = action(ctx, id)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondRaw(ctx, w, http.StatusNoContent)
+}
+
+type previewRunsResponse struct {
+	Runs []time.Time `json:"runs"`
+}
+
+// getScheduleRuns previews next runs of stored schedule, number of runs is set by count query parameter.
+func (srv *Server) getScheduleRuns(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	schedule, err := srv.app.GetSchedule(ctx, id)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+
+	runs, err := srv.app.PreviewRuns(schedule.Cron, schedule.Timezone, count)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: previewRunsResponse{Runs: runs}})
+}
+
+type previewRunsRequest struct {
+	Cron     string `json:"cron" validate:"required"`
+	Timezone string `json:"timezone"`
+	Count    int    `json:"count"`
+}
+
+// previewRuns previews next runs of cron expression that is not saved yet.
+func (srv *Server) previewRuns(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	request := new(previewRunsRequest)
+
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	if request.Timezone == "" {
+		request.Timezone = "UTC"
+	}
+
+	runs, err := srv.app.PreviewRuns(request.Cron, request.Timezone, request.Count)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: previewRunsResponse{Runs: runs}})
+}
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+// searchNotifications returns page of notifications visible to user matching query parameter q,
+// query parameters language, limit and cursor are optional.
+func (srv *Server) searchNotifications(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	q := r.URL.Query()
+
+	filter := &model.SearchFilter{
+		Query:    q.Get("q"),
+		Language: q.Get("language"),
+	}
+
+	if c := q.Get("cursor"); c != "" {
+		cursor, err := decodeSearchCursor(c)
+		if err != nil {
+			respondError(ctx, w, err)
+			return
+		}
+		filter.After = cursor
+	}
+
+	if l := q.Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil {
+			respondError(ctx, w, errors.Wrap(err, "parsing limit"))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	results, next, err := srv.app.SearchNotifications(ctx, sessionUser(ctx), filter)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: results, NextCursor: encodeSearchCursor(next)})
+}
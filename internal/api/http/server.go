@@ -17,7 +17,10 @@ import (
 	"go.uber.org/fx"
 
 	imiddleware "github.com/hummerd/gophercon/internal/api/http/middleware"
+	"github.com/hummerd/gophercon/internal/config"
 	"github.com/hummerd/gophercon/internal/controller"
+	"github.com/hummerd/gophercon/internal/events"
+	"github.com/hummerd/gophercon/internal/service"
 )
 
 var ()
@@ -25,12 +28,21 @@ var ()
 type Server struct {
 	*http.Server
 
-	app controller.App
+	app          *controller.App
+	sessionStore service.SessionStore
+	bus          *events.Bus
+
+	streamHeartbeat time.Duration
+	streamBuffer    int
+	socketOrigins   []string
 }
 
 func NewServer(
 	lc fx.Lifecycle,
-	app controller.App,
+	cfg *config.Config,
+	app *controller.App,
+	sessionStore service.SessionStore,
+	bus *events.Bus,
 ) *Server {
 	s := &Server{
 		Server: &http.Server{
@@ -39,7 +51,13 @@ func NewServer(
 			ReadTimeout:  time.Second * 10,
 			WriteTimeout: time.Second * 10,
 		},
-		app: app,
+		app:          app,
+		sessionStore: sessionStore,
+		bus:          bus,
+
+		streamHeartbeat: cfg.StreamHeartbeat,
+		streamBuffer:    cfg.StreamBuffer,
+		socketOrigins:   cfg.SocketOrigins,
 	}
 
 	lc.Append(
@@ -96,8 +114,88 @@ func Register(
 		r.Get("/log/level", srv.getLogLevel)
 		r.Put("/log/level", srv.setLogLevel)
 
-		r.Route("/notifications", func(r chi.Router) {
-			r.Post("/", count("notifications", srv.createNotification))
+		r.Group(func(r chi.Router) {
+			r.Use(imiddleware.Auth(srv.sessionStore, true))
+
+			r.Route("/notifications", func(r chi.Router) {
+				r.Get("/", count("notifications_inbox", srv.getNotifications))
+				r.Get("/search", count("notifications_search", srv.searchNotifications))
+				r.Get("/stream", srv.streamNotifications)
+				r.Get("/ws", srv.socketNotifications)
+				r.Post("/", count("notifications", srv.idempotent(srv.createNotification)))
+				r.Post("/bulk", count("notifications_bulk", srv.idempotent(srv.createNotificationsBulk)))
+				r.Post("/read", srv.markAllRead)
+				r.Post("/{id}/read", srv.markRead)
+				r.Get("/unread/count", srv.getUnreadCount)
+
+				r.With(imiddleware.RequireAdmin()).Patch("/{id}", srv.updateNotification)
+				r.With(imiddleware.RequireAdmin()).Delete("/{id}", srv.deleteNotification)
+			})
+
+			r.Route("/preferences", func(r chi.Router) {
+				r.Get("/", srv.getPreferences)
+				r.Put("/", srv.setPreferences)
+				r.Put("/quiet-hours", srv.setQuietHours)
+				r.Delete("/quiet-hours", srv.deleteQuietHours)
+				r.Put("/digest", srv.setDigest)
+				r.Delete("/digest", srv.deleteDigest)
+				r.Get("/digest/preview", srv.previewDigest)
+			})
+
+			r.Route("/devices", func(r chi.Router) {
+				r.Get("/", srv.getDevices)
+				r.Post("/", srv.registerDevice)
+				r.Delete("/{id}", srv.unregisterDevice)
+			})
+
+			r.Route("/templates", func(r chi.Router) {
+				r.Use(imiddleware.RequireAdmin())
+
+				r.Get("/", srv.getTemplates)
+				r.Post("/", srv.createTemplate)
+				r.Get("/{type}", srv.getTemplate)
+				r.Put("/{type}", srv.updateTemplate)
+				r.Delete("/{type}", srv.deleteTemplate)
+				r.Post("/{type}/preview", srv.previewTemplate)
+			})
+
+			r.Route("/schedules", func(r chi.Router) {
+				r.Use(imiddleware.RequireAdmin())
+
+				r.Get("/", srv.getSchedules)
+				r.Post("/", srv.createSchedule)
+				r.Post("/preview", srv.previewRuns)
+				r.Get("/{id}", srv.getSchedule)
+				r.Put("/{id}", srv.updateSchedule)
+				r.Delete("/{id}", srv.deleteSchedule)
+				r.Post("/{id}/pause", srv.pauseSchedule)
+				r.Post("/{id}/resume", srv.resumeSchedule)
+				r.Get("/{id}/preview", srv.getScheduleRuns)
+			})
+
+			r.Route("/webhooks", func(r chi.Router) {
+				r.Use(imiddleware.RequireAdmin())
+
+				r.Get("/", srv.getWebhooks)
+				r.Post("/", srv.createWebhook)
+				r.Get("/{id}", srv.getWebhook)
+				r.Put("/{id}", srv.updateWebhook)
+				r.Delete("/{id}", srv.deleteWebhook)
+				r.Get("/{id}/deliveries", srv.getWebhookDeliveries)
+				r.Get("/{id}/deliveries/{delivery}/attempts", srv.getWebhookAttempts)
+			})
+
+			r.Route("/admin", func(r chi.Router) {
+				r.Use(imiddleware.RequireAdmin())
+
+				r.Get("/notifications", srv.getAdminNotifications)
+				r.Get("/notifications/{id}/deliveries", srv.getNotificationDeliveries)
+
+				r.Get("/outbox", srv.getOutbox)
+				r.Post("/outbox/replay", srv.replayFailedOutbox)
+				r.Get("/outbox/{id}", srv.getOutboxEntry)
+				r.Post("/outbox/{id}/replay", srv.replayOutboxEntry)
+			})
 		})
 	})
 
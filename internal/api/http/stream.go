@@ -0,0 +1,190 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+const (
+	headerLastEventID = "Last-Event-ID"
+	mimeEventStream   = "text/event-stream"
+
+	// streamBackfillPage is a number of notifications requested at once on stream resume
+	streamBackfillPage = 100
+	// streamMaxBackfill limits number of notifications sent on stream resume, older ones are available in inbox
+	streamMaxBackfill = 1000
+	// streamWriteTimeout limits time of every write to the stream
+	streamWriteTimeout = 10 * time.Second
+)
+
+// streamNotifications pushes notifications published for the user as Server-Sent Events.
+// Client that reconnects with Last-Event-ID header receives notifications published since that event.
+// Idle stream gets heartbeat comments, stream that can't keep up with events is closed.
+func (srv *Server) streamNotifications(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := zerolog.Ctx(ctx)
+
+	var (
+		pos *model.StreamPosition
+		err error
+	)
+
+	if id := r.Header.Get(headerLastEventID); id != "" {
+		pos, err = decodeStreamPosition(id)
+		if err != nil {
+			respondError(ctx, w, err)
+			return
+		}
+	}
+
+	// Stream outlives server's read and write timeouts, write deadline is set for every write instead
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Time{}); err != nil {
+		logger.Error().Err(err).Msg("can not reset read deadline of the stream")
+		respondRaw(ctx, w, http.StatusInternalServerError)
+		return
+	}
+
+	user := sessionUser(ctx)
+	user.Locales = preferredLocales(r)
+
+	if err := srv.app.ApplyPreferences(ctx, user); err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	// Subscribe before backfill so no notification is lost in between
+	sub := srv.bus.Subscribe(srv.streamBuffer)
+	defer sub.Close()
+
+	sw := &sseWriter{w: w, rc: rc}
+
+	w.Header().Set(headerContentType, mimeEventStream)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	sent, err := srv.backfill(ctx, user, pos, sw.send)
+	if err != nil {
+		logger.Warn().Err(err).Msg("can not backfill notifications stream")
+		return
+	}
+
+	if err := sw.flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(srv.streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case e, ok := <-sub.C:
+			if !ok {
+				logger.Warn().Err(sub.Err()).Msg("notifications stream is closed")
+				return
+			}
+
+			n := e.Notification
+			if !n.VisibleTo(user.ID, time.Now()) || user.Mutes(n) {
+				continue
+			}
+
+			if _, ok := sent[n.ID]; ok {
+				delete(sent, n.ID)
+				continue
+			}
+
+			if err := sw.send(srv.app.Localize(user, n)); err != nil {
+				return
+			}
+
+		case <-heartbeat.C:
+			if err := sw.heartbeat(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// backfill sends notifications published after pos, it returns ids of sent notifications
+// so that the same notifications received from events bus can be skipped.
+func (srv *Server) backfill(
+	ctx context.Context,
+	user *model.User,
+	pos *model.StreamPosition,
+	send func(*model.Notification) error,
+) (map[int]struct{}, error) {
+	sent := make(map[int]struct{})
+
+	for pos != nil && len(sent) < streamMaxBackfill {
+		notifications, err := srv.app.GetPublishedAfter(ctx, user, pos, streamBackfillPage)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, n := range notifications {
+			if err := send(n); err != nil {
+				return nil, err
+			}
+			sent[n.ID] = struct{}{}
+		}
+
+		if len(notifications) < streamBackfillPage {
+			break
+		}
+
+		last := notifications[len(notifications)-1]
+		pos = &model.StreamPosition{PublishSeq: *last.PublishSeq}
+	}
+
+	return sent, nil
+}
+
+type sseWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func (sw *sseWriter) send(n *model.Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	return sw.write("id: %s\nevent: notification\ndata: %s\n\n", encodeStreamPosition(n), body)
+}
+
+func (sw *sseWriter) heartbeat() error {
+	return sw.write(": heartbeat\n\n")
+}
+
+func (sw *sseWriter) write(format string, args ...interface{}) error {
+	if err := sw.rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(sw.w, format, args...); err != nil {
+		return err
+	}
+
+	return sw.rc.Flush()
+}
+
+func (sw *sseWriter) flush() error {
+	if err := sw.rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil {
+		return err
+	}
+
+	return sw.rc.Flush()
+}
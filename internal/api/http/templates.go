@@ -0,0 +1,125 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+type templateRequest struct {
+	Type  string `json:"type" validate:"required"`
+	Title string `json:"title" validate:"required"`
+	Body  string `json:"body" validate:"required"`
+}
+
+func (srv *Server) createTemplate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	request := new(templateRequest)
+
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	tmpl := &model.Template{
+		Type:  request.Type,
+		Title: request.Title,
+		Body:  request.Body,
+	}
+
+	err := srv.app.CreateTemplate(ctx, tmpl)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: tmpl})
+}
+
+func (srv *Server) getTemplates(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	templates, err := srv.app.ListTemplates(ctx)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: templates})
+}
+
+func (srv *Server) getTemplate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	tmpl, err := srv.app.GetTemplate(ctx, chi.URLParam(r, "type"))
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: tmpl})
+}
+
+func (srv *Server) updateTemplate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	request := new(templateRequest)
+
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	tmpl := &model.Template{
+		Type:  chi.URLParam(r, "type"),
+		Title: request.Title,
+		Body:  request.Body,
+	}
+
+	err := srv.app.UpdateTemplate(ctx, tmpl)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: tmpl})
+}
+
+func (srv *Server) deleteTemplate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	err := srv.app.DeleteTemplate(ctx, chi.URLParam(r, "type"))
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondRaw(ctx, w, http.StatusNoContent)
+}
+
+type previewTemplateRequest struct {
+	Variables map[string]string `json:"variables"`
+}
+
+func (srv *Server) previewTemplate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	request := new(previewTemplateRequest)
+
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	notification, err := srv.app.RenderNotification(ctx, chi.URLParam(r, "type"), request.Variables)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: notification})
+}
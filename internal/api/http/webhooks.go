@@ -0,0 +1,184 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+type webhookRequest struct {
+	URL    string   `json:"url" validate:"required,url"`
+	Events []string `json:"events"`
+	Types  []string `json:"types"`
+	Secret string   `json:"secret"`
+	Active *bool    `json:"active"`
+}
+
+func (req *webhookRequest) webhook() *model.Webhook {
+	webhook := &model.Webhook{
+		URL:    req.URL,
+		Events: req.Events,
+		Types:  req.Types,
+		Secret: req.Secret,
+		Active: true,
+	}
+
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+
+	return webhook
+}
+
+// createWebhookResponse is the only response that contains webhook's secret
+type createWebhookResponse struct {
+	*model.Webhook
+	Secret string `json:"secret"`
+}
+
+func (srv *Server) createWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	request := new(webhookRequest)
+
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	webhook := request.webhook()
+
+	err := srv.app.CreateWebhook(ctx, webhook)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: createWebhookResponse{Webhook: webhook, Secret: webhook.Secret}})
+}
+
+func (srv *Server) getWebhooks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	webhooks, err := srv.app.ListWebhooks(ctx)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: webhooks})
+}
+
+func (srv *Server) getWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	webhook, err := srv.app.GetWebhook(ctx, id)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: webhook})
+}
+
+// updateWebhook replaces webhook, secret is rotated only when request contains one.
+func (srv *Server) updateWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	request := new(webhookRequest)
+
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	webhook := request.webhook()
+	webhook.ID = id
+
+	err = srv.app.UpdateWebhook(ctx, webhook)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: webhook})
+}
+
+func (srv *Server) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	err = srv.app.DeleteWebhook(ctx, id)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondRaw(ctx, w, http.StatusNoContent)
+}
+
+// getWebhookDeliveries returns latest deliveries of webhook, number of deliveries is set by limit query parameter.
+func (srv *Server) getWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	deliveries, err := srv.app.ListWebhookDeliveries(ctx, id, limit)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: deliveries})
+}
+
+func (srv *Server) getWebhookAttempts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	deliveryID, err := strconv.Atoi(chi.URLParam(r, "delivery"))
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	attempts, err := srv.app.ListWebhookAttempts(ctx, id, deliveryID)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	respondOK(ctx, w, data{Data: attempts})
+}
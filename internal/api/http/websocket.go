@@ -0,0 +1,260 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/hummerd/gophercon/internal/dataprovider"
+	"github.com/hummerd/gophercon/internal/events"
+	"github.com/hummerd/gophercon/internal/model"
+	"github.com/hummerd/gophercon/internal/websocket"
+)
+
+const (
+	// socketReadLimit limits size of messages received from client
+	socketReadLimit = 4 << 10
+
+	socketMessageRead    = "read"
+	socketMessageReadAll = "read_all"
+	socketMessageError   = "error"
+	socketMessageEvent   = "notification"
+)
+
+var errUnknownSocketMessage = errors.New("unknown message type")
+
+// Error texts sent to client, internal errors are only logged.
+const (
+	socketErrorNotText  = "text message expected"
+	socketErrorInvalid  = "invalid message"
+	socketErrorNotFound = "notification not found"
+	socketErrorInternal = "internal error"
+)
+
+// socketMessage is a message received from client.
+type socketMessage struct {
+	Type string `json:"type"`
+	ID   int    `json:"id,omitempty"`
+}
+
+// socketReply is a message sent to client: a notification or result of client's message.
+type socketReply struct {
+	Type         string              `json:"type"`
+	ID           int                 `json:"id,omitempty"`
+	Position     string              `json:"position,omitempty"`
+	Notification *model.Notification `json:"notification,omitempty"`
+	Error        string              `json:"error,omitempty"`
+}
+
+// socketNotifications pushes notifications published for the user over WebSocket
+// and accepts read acknowledgements from client.
+// Client that reconnects with "after" query parameter set to position of the last received notification
+// receives notifications published since that notification.
+// Connection is pinged every heartbeat interval and closed if client does not answer,
+// connection that can't keep up with events is closed.
+func (srv *Server) socketNotifications(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := zerolog.Ctx(ctx)
+
+	var (
+		pos *model.StreamPosition
+		err error
+	)
+
+	if after := r.URL.Query().Get("after"); after != "" {
+		pos, err = decodeStreamPosition(after)
+		if err != nil {
+			respondError(ctx, w, err)
+			return
+		}
+	}
+
+	user := sessionUser(ctx)
+	user.Locales = preferredLocales(r)
+
+	if err := srv.app.ApplyPreferences(ctx, user); err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	// Subscribe before backfill so no notification is lost in between
+	sub := srv.bus.Subscribe(srv.streamBuffer)
+	defer sub.Close()
+
+	conn, err := websocket.Upgrade(w, r, srv.socketOrigins)
+	if err != nil {
+		logger.Warn().Err(err).Msg("can not upgrade to websocket")
+		return
+	}
+	defer conn.Close()
+
+	conn.ReadLimit = socketReadLimit
+	conn.WriteTimeout = streamWriteTimeout
+
+	// Hijacked connection is not watched by http server anymore,
+	// socket is done when either reading or writing fails
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s := &socket{
+		conn:    conn,
+		replies: make(chan *socketReply, srv.streamBuffer),
+	}
+
+	go func() {
+		defer cancel()
+		s.read(ctx, srv, user, 2*srv.streamHeartbeat)
+	}()
+
+	sent, err := srv.backfill(ctx, user, pos, s.send)
+	if err != nil {
+		logger.Warn().Err(err).Msg("can not backfill notifications socket")
+		conn.WriteClose(websocket.CloseInternalError, "")
+		return
+	}
+
+	ping := time.NewTicker(srv.streamHeartbeat)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case e, ok := <-sub.C:
+			if !ok {
+				logger.Warn().Err(sub.Err()).Msg("notifications socket is closed")
+				conn.WriteClose(closeCode(sub.Err()), "")
+				return
+			}
+
+			n := e.Notification
+			if !n.VisibleTo(user.ID, time.Now()) || user.Mutes(n) {
+				continue
+			}
+
+			if _, ok := sent[n.ID]; ok {
+				delete(sent, n.ID)
+				continue
+			}
+
+			if err := s.send(srv.app.Localize(user, n)); err != nil {
+				return
+			}
+
+		case reply := <-s.replies:
+			if err := s.write(reply); err != nil {
+				return
+			}
+
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.OpPing, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+type socket struct {
+	conn *websocket.Conn
+	// replies buffers results of client's messages
+	replies chan *socketReply
+}
+
+func (s *socket) send(n *model.Notification) error {
+	return s.write(&socketReply{
+		Type:         socketMessageEvent,
+		ID:           n.ID,
+		Position:     encodeStreamPosition(n),
+		Notification: n,
+	})
+}
+
+func (s *socket) write(reply *socketReply) error {
+	body, err := json.Marshal(reply)
+	if err != nil {
+		return err
+	}
+
+	return s.conn.WriteMessage(websocket.OpText, body)
+}
+
+// read handles client's messages until connection fails or client stops answering pings.
+func (s *socket) read(ctx context.Context, srv *Server, user *model.User, pongWait time.Duration) {
+	logger := zerolog.Ctx(ctx)
+
+	s.conn.SetReadDeadline(time.Now().Add(pongWait))
+	s.conn.OnPong = func([]byte) {
+		s.conn.SetReadDeadline(time.Now().Add(pongWait))
+	}
+
+	for {
+		op, body, err := s.conn.ReadMessage()
+		if err != nil {
+			logger.Debug().Err(err).Msg("notifications socket read finished")
+			return
+		}
+
+		s.conn.SetReadDeadline(time.Now().Add(pongWait))
+
+		reply := &socketReply{}
+		msg := &socketMessage{}
+
+		if op != websocket.OpText {
+			reply.Type = socketMessageError
+			reply.Error = socketErrorNotText
+		} else if err := json.Unmarshal(body, msg); err != nil {
+			reply.Type = socketMessageError
+			reply.Error = socketErrorInvalid
+		} else {
+			reply.Type = msg.Type
+			reply.ID = msg.ID
+
+			switch msg.Type {
+			case socketMessageRead:
+				err = srv.app.MarkRead(ctx, user, msg.ID)
+			case socketMessageReadAll:
+				err = srv.app.MarkAllRead(ctx, user)
+			default:
+				err = errUnknownSocketMessage
+			}
+
+			if err != nil {
+				reply.Type = socketMessageError
+				reply.Error = socketErrorText(ctx, err)
+			}
+		}
+
+		// Client that does not read its replies is as slow as one that does not read notifications
+		select {
+		case s.replies <- reply:
+		default:
+			s.conn.WriteClose(websocket.CloseTryAgainLater, "too many messages")
+			return
+		}
+	}
+}
+
+// socketErrorText maps error of client's message to text safe to send to client.
+func socketErrorText(ctx context.Context, err error) string {
+	switch errors.Cause(err) {
+	case errUnknownSocketMessage:
+		return errUnknownSocketMessage.Error()
+	case dataprovider.ErrNotFound:
+		return socketErrorNotFound
+	default:
+		zerolog.Ctx(ctx).Error().Err(err).Msg("can not handle socket message")
+		return socketErrorInternal
+	}
+}
+
+func closeCode(err error) int {
+	if err == events.ErrSlowConsumer {
+		return websocket.CloseTryAgainLater
+	}
+	return websocket.CloseGoingAway
+}
@@ -3,24 +3,26 @@ package main
 import (
 	"context"
 
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"go.uber.org/fx"
 
 	httpapi "github.com/hummerd/gophercon/internal/api/http"
+	"github.com/hummerd/gophercon/internal/config"
 	"github.com/hummerd/gophercon/internal/controller"
+	"github.com/hummerd/gophercon/internal/dataprovider"
 	"github.com/hummerd/gophercon/internal/dataprovider/pg"
+	"github.com/hummerd/gophercon/internal/events"
+	"github.com/hummerd/gophercon/internal/service"
 	httpservice "github.com/hummerd/gophercon/internal/service/http"
+	smtpservice "github.com/hummerd/gophercon/internal/service/smtp"
 )
 
 func main() {
 	app := fx.New(
 		fx.NopLogger,
-		fx.Provide(
-			httpapi.NewServer,
-			pg.NewNotificationStore,
-			httpservice.NewSessionStore,
-			controller.NewApp,
-		),
+		options(),
 	)
 
 	ctx, cancel := context.WithTimeout(context.Background(), fx.DefaultTimeout)
@@ -28,11 +30,124 @@ func main() {
 
 	if err := app.Start(ctx); err != nil {
 		log.Error().Err(err).Msg("Can not start service")
+		return
 	}
 
+	<-app.Done()
+
 	ctxStop, cancelStop := context.WithTimeout(context.Background(), fx.DefaultTimeout)
 	defer cancelStop()
 	if err := app.Stop(ctxStop); err != nil {
 		return
 	}
 }
+
+// options provides service's components, registers API routes and starts workers.
+func options() fx.Option {
+	return fx.Options(
+		fx.Provide(
+			config.New,
+			newDB,
+			httpapi.NewServer,
+			pg.NewNotificationStore,
+			pg.NewTemplateStore,
+			pg.NewScheduleStore,
+			pg.NewNotificationListener,
+			newNotifyDialer,
+			pg.NewWebhookStore,
+			pg.NewDeliveryStore,
+			pg.NewDeviceStore,
+			pg.NewOutboxStore,
+			pg.NewIdempotencyStore,
+			pg.NewPreferenceStore,
+			pg.NewDigestStore,
+			httpservice.NewSessionStore,
+			httpservice.NewWebhookSender,
+			httpservice.NewUserStore,
+			smtpservice.NewEmailChannel,
+			httpservice.NewPushProviders,
+			controller.NewPushChannel,
+			newChannels,
+			controller.NewApp,
+			controller.NewDispatcher,
+			controller.NewScheduler,
+			controller.NewHub,
+			controller.NewWebhookDeliverer,
+			controller.NewChannelDeliverer,
+			controller.NewOutboxRelay,
+			controller.NewIdempotencyPurger,
+			newEventPublisher,
+			events.NewBus,
+		),
+		bindings(),
+		fx.Invoke(func(
+			srv *httpapi.Server,
+			_ *controller.Dispatcher,
+			_ *controller.Scheduler,
+			_ *controller.Hub,
+			_ *controller.WebhookDeliverer,
+			_ *controller.ChannelDeliverer,
+			_ *controller.OutboxRelay,
+			_ *controller.IdempotencyPurger,
+		) error {
+			return httpapi.Register(srv)
+		}),
+	)
+}
+
+// bindings provide components as interfaces they are consumed through.
+func bindings() fx.Option {
+	return fx.Provide(
+		func(s *pg.NotificationStore) dataprovider.NotificationStore { return s },
+		func(s *pg.TemplateStore) dataprovider.TemplateStore { return s },
+		func(s *pg.ScheduleStore) dataprovider.ScheduleStore { return s },
+		func(l *pg.NotificationListener) dataprovider.NotificationListener { return l },
+		func(s *pg.WebhookStore) dataprovider.WebhookStore { return s },
+		func(s *pg.DeliveryStore) dataprovider.DeliveryStore { return s },
+		func(s *pg.DeviceStore) dataprovider.DeviceStore { return s },
+		func(s *pg.OutboxStore) dataprovider.OutboxStore { return s },
+		func(s *pg.IdempotencyStore) dataprovider.IdempotencyStore { return s },
+		func(s *pg.PreferenceStore) dataprovider.PreferenceStore { return s },
+		func(s *pg.DigestStore) dataprovider.DigestStore { return s },
+		func(s *httpservice.SessionStore) service.SessionStore { return s },
+		func(s *httpservice.WebhookSender) service.WebhookSender { return s },
+		func(s *httpservice.UserStore) service.UserStore { return s },
+	)
+}
+
+// newDB opens pool of connections to configured database, pool is closed on application stop.
+func newDB(lc fx.Lifecycle, cfg *config.Config) (sqlx.ExtContext, error) {
+	db, err := sqlx.Open("postgres", cfg.DatabaseURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening database")
+	}
+
+	lc.Append(
+		fx.Hook{
+			OnStop: func(context.Context) error {
+				return db.Close()
+			},
+		},
+	)
+
+	return db, nil
+}
+
+// newEventPublisher delivers events relayed from outbox to webhooks and delivery channels.
+// Live subscribers get published notifications from Hub.
+func newEventPublisher(
+	webhooks *controller.WebhookDeliverer,
+	channels *controller.ChannelDeliverer,
+) service.EventPublisher {
+	return service.EventPublishers{webhooks, channels}
+}
+
+// newNotifyDialer connects listener of published notifications to configured database.
+func newNotifyDialer(cfg *config.Config) pg.NotifyDialer {
+	return pg.NewPQDialer(cfg.DatabaseURL)
+}
+
+// newChannels lists delivery channels available to ChannelDeliverer.
+func newChannels(email *smtpservice.EmailChannel, push *controller.PushChannel) service.Channels {
+	return service.Channels{email, push}
+}
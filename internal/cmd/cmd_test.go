@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+
+	"go.uber.org/fx"
+
+	httpapi "github.com/hummerd/gophercon/internal/api/http"
+)
+
+func TestOptions(t *testing.T) {
+	var srv *httpapi.Server
+
+	app := fx.New(fx.NopLogger, options(), fx.Populate(&srv))
+
+	if err := app.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if srv.Handler == nil {
+		t.Fatal("expected API routes to be registered")
+	}
+}
@@ -0,0 +1,240 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds service settings, settings are read from environment variables.
+type Config struct {
+	// DatabaseURL is a postgres connection string, either URL or key=value pairs.
+	DatabaseURL string
+
+	// FallbackLocales is a chain of locales used when none of user's preferred locales is available.
+	FallbackLocales []string
+
+	// DispatchInterval is a pause between checks for scheduled notifications.
+	DispatchInterval time.Duration
+	// DispatchBatchSize limits number of notifications published in single transaction.
+	DispatchBatchSize int
+
+	// ScheduleInterval is a pause between checks for due recurring schedules.
+	ScheduleInterval time.Duration
+	// ScheduleCatchUp is default policy for schedule runs missed during downtime, "skip" or "all".
+	ScheduleCatchUp string
+	// ScheduleMaxCatchUp limits number of missed runs produced by "all" policy.
+	ScheduleMaxCatchUp int
+
+	// StreamHeartbeat is an interval of heartbeats sent to idle notification streams.
+	StreamHeartbeat time.Duration
+	// StreamBuffer is a number of events buffered for a stream, slower streams are closed.
+	StreamBuffer int
+	// SocketOrigins are origins of pages allowed to open notifications WebSocket besides service's own origin.
+	SocketOrigins []string
+
+	// ListenReconnect is an initial pause before reconnecting lost listener of published notifications,
+	// pause doubles with every failed attempt.
+	ListenReconnect time.Duration
+
+	// WebhookInterval is a pause between checks for due webhook deliveries.
+	WebhookInterval time.Duration
+	// WebhookBatchSize limits number of webhook deliveries sent concurrently.
+	WebhookBatchSize int
+	// WebhookMaxAttempts is a number of attempts after which delivery is failed.
+	WebhookMaxAttempts int
+	// WebhookRetryBase is a pause before the second attempt, pause doubles with every failed attempt.
+	WebhookRetryBase time.Duration
+	// WebhookRetryMax limits pause between attempts.
+	WebhookRetryMax time.Duration
+
+	// ChannelInterval is a pause between checks for due channel deliveries (email, push...).
+	ChannelInterval time.Duration
+	// ChannelBatchSize limits number of channel deliveries sent concurrently.
+	ChannelBatchSize int
+	// ChannelMaxAttempts is a number of attempts after which channel delivery is failed.
+	ChannelMaxAttempts int
+	// ChannelRetryBase is a pause before the second attempt, pause doubles with every failed attempt.
+	ChannelRetryBase time.Duration
+	// ChannelRetryMax limits pause between attempts.
+	ChannelRetryMax time.Duration
+
+	// OutboxInterval is a pause between checks for pending outbox entries.
+	OutboxInterval time.Duration
+	// OutboxBatchSize limits number of outbox entries claimed at once.
+	OutboxBatchSize int
+	// OutboxMaxAttempts is a number of attempts after which outbox entry is failed.
+	OutboxMaxAttempts int
+	// OutboxRetryBase is a pause before the second attempt, pause doubles with every failed attempt.
+	OutboxRetryBase time.Duration
+	// OutboxRetryMax limits pause between attempts.
+	OutboxRetryMax time.Duration
+
+	// IdempotencyTTL is a time responses of requests with idempotency key are stored.
+	IdempotencyTTL time.Duration
+	// IdempotencyLease is a time key of unfinished request is held, it must exceed request timeout.
+	IdempotencyLease time.Duration
+	// IdempotencyWait limits time request waits for in-flight request with the same key.
+	IdempotencyWait time.Duration
+	// IdempotencyPurgeInterval is a pause between deletions of expired keys.
+	IdempotencyPurgeInterval time.Duration
+
+	// EmailTypes are notification types that are delivered by email as well.
+	EmailTypes []string
+	// SMTPAddr is a host:port of SMTP server.
+	SMTPAddr string
+	// SMTPTLS is "starttls" or "none", STARTTLS is required by "starttls".
+	SMTPTLS string
+	// SMTPUsername and SMTPPassword are used for PLAIN authentication when username is set.
+	SMTPUsername string
+	SMTPPassword string
+	// SMTPFrom is a sender address, it may include display name.
+	SMTPFrom string
+	// SMTPTimeout limits time of sending single email.
+	SMTPTimeout time.Duration
+	// EmailTextTemplate and EmailHTMLTemplate are paths to templates of email parts,
+	// built-in templates are used when they are not set.
+	EmailTextTemplate string
+	EmailHTMLTemplate string
+
+	// MandatoryTypes are notification types users can't disable in preferences.
+	MandatoryTypes []string
+
+	// DigestTime is a local time of daily and weekly digests in HH:MM format,
+	// weekly digests are sent on Mondays.
+	DigestTime string
+
+	// SearchLanguage is a postgres text search configuration notifications are indexed with
+	// unless they specify their own language, it is a default language of search queries as well.
+	SearchLanguage string
+
+	// PushTypes are notification types that are delivered by push as well.
+	PushTypes []string
+	// FCMBaseURL is a base url of FCM HTTP v1 API.
+	FCMBaseURL string
+	// FCMCredentials is a path to service account json, FCM is disabled when it is not set.
+	FCMCredentials string
+	// FCMTokenURL replaces OAuth2 token url of service account when it is set.
+	FCMTokenURL string
+	// APNsBaseURL is a base url of APNs API.
+	APNsBaseURL string
+	// APNsKey is a path to token signing key (.p8), APNs is disabled when it is not set.
+	APNsKey string
+	// APNsKeyID and APNsTeamID identify signing key.
+	APNsKeyID  string
+	APNsTeamID string
+	// APNsTopic is a bundle id of application.
+	APNsTopic string
+}
+
+// New reads config from environment.
+func New() *Config {
+	return &Config{
+		DatabaseURL: getString("NOTIFICATIONS_DATABASE_URL", "postgres://localhost:5432/notifications?sslmode=disable"),
+
+		FallbackLocales:   getList("NOTIFICATIONS_FALLBACK_LOCALES", []string{"en"}),
+		DispatchInterval:  getDuration("NOTIFICATIONS_DISPATCH_INTERVAL", 5*time.Second),
+		DispatchBatchSize: getInt("NOTIFICATIONS_DISPATCH_BATCH_SIZE", 100),
+
+		ScheduleInterval:   getDuration("NOTIFICATIONS_SCHEDULE_INTERVAL", 30*time.Second),
+		ScheduleCatchUp:    getString("NOTIFICATIONS_SCHEDULE_CATCH_UP", "skip"),
+		ScheduleMaxCatchUp: getInt("NOTIFICATIONS_SCHEDULE_MAX_CATCH_UP", 100),
+
+		StreamHeartbeat: getDuration("NOTIFICATIONS_STREAM_HEARTBEAT", 15*time.Second),
+		StreamBuffer:    getInt("NOTIFICATIONS_STREAM_BUFFER", 64),
+		SocketOrigins:   getList("NOTIFICATIONS_SOCKET_ORIGINS", nil),
+
+		ListenReconnect: getDuration("NOTIFICATIONS_LISTEN_RECONNECT", time.Second),
+
+		WebhookInterval:    getDuration("NOTIFICATIONS_WEBHOOK_INTERVAL", 5*time.Second),
+		WebhookBatchSize:   getInt("NOTIFICATIONS_WEBHOOK_BATCH_SIZE", 50),
+		WebhookMaxAttempts: getInt("NOTIFICATIONS_WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookRetryBase:   getDuration("NOTIFICATIONS_WEBHOOK_RETRY_BASE", 30*time.Second),
+		WebhookRetryMax:    getDuration("NOTIFICATIONS_WEBHOOK_RETRY_MAX", time.Hour),
+
+		ChannelInterval:    getDuration("NOTIFICATIONS_CHANNEL_INTERVAL", 5*time.Second),
+		ChannelBatchSize:   getInt("NOTIFICATIONS_CHANNEL_BATCH_SIZE", 50),
+		ChannelMaxAttempts: getInt("NOTIFICATIONS_CHANNEL_MAX_ATTEMPTS", 5),
+		ChannelRetryBase:   getDuration("NOTIFICATIONS_CHANNEL_RETRY_BASE", time.Minute),
+		ChannelRetryMax:    getDuration("NOTIFICATIONS_CHANNEL_RETRY_MAX", time.Hour),
+
+		OutboxInterval:    getDuration("NOTIFICATIONS_OUTBOX_INTERVAL", time.Second),
+		OutboxBatchSize:   getInt("NOTIFICATIONS_OUTBOX_BATCH_SIZE", 100),
+		OutboxMaxAttempts: getInt("NOTIFICATIONS_OUTBOX_MAX_ATTEMPTS", 10),
+		OutboxRetryBase:   getDuration("NOTIFICATIONS_OUTBOX_RETRY_BASE", 5*time.Second),
+		OutboxRetryMax:    getDuration("NOTIFICATIONS_OUTBOX_RETRY_MAX", 10*time.Minute),
+
+		IdempotencyTTL:           getDuration("NOTIFICATIONS_IDEMPOTENCY_TTL", 24*time.Hour),
+		IdempotencyLease:         getDuration("NOTIFICATIONS_IDEMPOTENCY_LEASE", time.Minute),
+		IdempotencyWait:          getDuration("NOTIFICATIONS_IDEMPOTENCY_WAIT", 5*time.Second),
+		IdempotencyPurgeInterval: getDuration("NOTIFICATIONS_IDEMPOTENCY_PURGE_INTERVAL", 10*time.Minute),
+
+		EmailTypes:        getList("NOTIFICATIONS_EMAIL_TYPES", nil),
+		SMTPAddr:          getString("NOTIFICATIONS_SMTP_ADDR", "localhost:25"),
+		SMTPTLS:           getString("NOTIFICATIONS_SMTP_TLS", "starttls"),
+		SMTPUsername:      getString("NOTIFICATIONS_SMTP_USERNAME", ""),
+		SMTPPassword:      getString("NOTIFICATIONS_SMTP_PASSWORD", ""),
+		SMTPFrom:          getString("NOTIFICATIONS_SMTP_FROM", "noreply@example.com"),
+		SMTPTimeout:       getDuration("NOTIFICATIONS_SMTP_TIMEOUT", 30*time.Second),
+		EmailTextTemplate: getString("NOTIFICATIONS_EMAIL_TEXT_TEMPLATE", ""),
+		EmailHTMLTemplate: getString("NOTIFICATIONS_EMAIL_HTML_TEMPLATE", ""),
+
+		MandatoryTypes: getList("NOTIFICATIONS_MANDATORY_TYPES", nil),
+
+		DigestTime: getString("NOTIFICATIONS_DIGEST_TIME", "09:00"),
+
+		SearchLanguage: getString("NOTIFICATIONS_SEARCH_LANGUAGE", "simple"),
+
+		PushTypes:      getList("NOTIFICATIONS_PUSH_TYPES", nil),
+		FCMBaseURL:     getString("NOTIFICATIONS_FCM_BASE_URL", "https://fcm.googleapis.com"),
+		FCMCredentials: getString("NOTIFICATIONS_FCM_CREDENTIALS", ""),
+		FCMTokenURL:    getString("NOTIFICATIONS_FCM_TOKEN_URL", ""),
+		APNsBaseURL:    getString("NOTIFICATIONS_APNS_BASE_URL", "https://api.push.apple.com"),
+		APNsKey:        getString("NOTIFICATIONS_APNS_KEY", ""),
+		APNsKeyID:      getString("NOTIFICATIONS_APNS_KEY_ID", ""),
+		APNsTeamID:     getString("NOTIFICATIONS_APNS_TEAM_ID", ""),
+		APNsTopic:      getString("NOTIFICATIONS_APNS_TOPIC", ""),
+	}
+}
+
+func getString(key string, def string) string {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	return v
+}
+
+func getList(key string, def []string) []string {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	list := strings.Split(v, ",")
+	for i := range list {
+		list[i] = strings.TrimSpace(list[i])
+	}
+
+	return list
+}
+
+func getDuration(key string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || d <= 0 {
+		return def
+	}
+
+	return d
+}
+
+func getInt(key string, def int) int {
+	n, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || n <= 0 {
+		return def
+	}
+
+	return n
+}
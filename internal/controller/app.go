@@ -2,9 +2,11 @@ package controller
 
 import (
 	"context"
+	"time"
 
 	"github.com/pkg/errors"
 
+	"github.com/hummerd/gophercon/internal/config"
 	"github.com/hummerd/gophercon/internal/dataprovider"
 	"github.com/hummerd/gophercon/internal/model"
 	"github.com/hummerd/gophercon/internal/service"
@@ -12,16 +14,67 @@ import (
 
 const (
 	sessionTypeToken = "token"
+
+	defaultPageSize = 20
+	maxPageSize     = 100
+
+	maxBulkSize = 10000
+
+	maxCollapseKeyLength = 255
+)
+
+var (
+	// ErrInvalidVisibilityWindow is returned when notification's FromTime is not before TillTime.
+	ErrInvalidVisibilityWindow = errors.New("from_time must be before till_time")
+	// ErrInvalidState is returned when unknown notification state is requested.
+	ErrInvalidState = errors.New("unknown notification state")
+	// ErrMissingContent is returned when notification has no title, body or type.
+	ErrMissingContent = errors.New("title, body and type are required")
+	// ErrInvalidPriority is returned when notification has unknown priority.
+	ErrInvalidPriority = errors.New("unknown priority")
+	// ErrInvalidTranslation is returned when translation has no locale, title or body.
+	ErrInvalidTranslation = errors.New("translation must have locale, title and body")
+	// ErrBulkTooLarge is returned when too many notifications are created at once.
+	ErrBulkTooLarge = errors.New("too many notifications in bulk")
+	// ErrInvalidCollapseKey is returned when notification's collapse key is too long.
+	ErrInvalidCollapseKey = errors.New("collapse key must not exceed 255 characters")
 )
 
 // NewApp creates an instance of App controller
 func NewApp(
+	cfg *config.Config,
 	sessionStore service.SessionStore,
 	notificationStore dataprovider.NotificationStore,
+	templateStore dataprovider.TemplateStore,
+	scheduleStore dataprovider.ScheduleStore,
+	webhookStore dataprovider.WebhookStore,
+	deliveryStore dataprovider.DeliveryStore,
+	deviceStore dataprovider.DeviceStore,
+	outboxStore dataprovider.OutboxStore,
+	idempotencyStore dataprovider.IdempotencyStore,
+	preferenceStore dataprovider.PreferenceStore,
+	digestStore dataprovider.DigestStore,
 ) *App {
 	h := App{
 		sessionStore:      sessionStore,
 		notificationStore: notificationStore,
+		templateStore:     templateStore,
+		scheduleStore:     scheduleStore,
+		webhookStore:      webhookStore,
+		deliveryStore:     deliveryStore,
+		deviceStore:       deviceStore,
+		outboxStore:       outboxStore,
+		idempotencyStore:  idempotencyStore,
+		preferenceStore:   preferenceStore,
+		digestStore:       digestStore,
+		fallbackLocales:   cfg.FallbackLocales,
+		catchUp:           cfg.ScheduleCatchUp,
+		mandatoryTypes:    cfg.MandatoryTypes,
+		idempotencyTTL:    cfg.IdempotencyTTL,
+		idempotencyLease:  cfg.IdempotencyLease,
+		idempotencyWait:   cfg.IdempotencyWait,
+		digestAt:          digestAt(cfg),
+		searchLanguage:    cfg.SearchLanguage,
 	}
 
 	return &h
@@ -30,9 +83,48 @@ func NewApp(
 type App struct {
 	sessionStore      service.SessionStore
 	notificationStore dataprovider.NotificationStore
+	templateStore     dataprovider.TemplateStore
+	scheduleStore     dataprovider.ScheduleStore
+	webhookStore      dataprovider.WebhookStore
+	deliveryStore     dataprovider.DeliveryStore
+	deviceStore       dataprovider.DeviceStore
+	outboxStore       dataprovider.OutboxStore
+	idempotencyStore  dataprovider.IdempotencyStore
+	preferenceStore   dataprovider.PreferenceStore
+	digestStore       dataprovider.DigestStore
+
+	fallbackLocales []string
+	// catchUp is default catch-up policy of schedules
+	catchUp string
+	// mandatoryTypes are notification types users can't disable
+	mandatoryTypes []string
+
+	// idempotencyTTL is a time response of request is replayed for requests with the same key
+	idempotencyTTL time.Duration
+	// idempotencyLease is a time key of unfinished request is held
+	idempotencyLease time.Duration
+	// idempotencyWait limits time request waits for in-flight request with the same key
+	idempotencyWait time.Duration
+
+	// digestAt is a local time of daily and weekly digests in minutes since midnight
+	digestAt int
+	// searchLanguage is a default text search configuration of notifications and queries
+	searchLanguage string
 }
 
+// CreateNotification creates notification, notification without PublishAt or with PublishAt
+// in the past is published immediately, otherwise it is published later by Dispatcher.
+// Notification with collapse key may be merged into existing notification, its id is returned then.
+// Event of published notification is stored along with notification and relayed by OutboxRelay.
 func (ha *App) CreateNotification(ctx context.Context, notification *model.Notification) error {
+	ha.setLanguage(notification)
+
+	if err := validateNotification(notification); err != nil {
+		return err
+	}
+
+	schedule(notification, time.Now())
+
 	err := ha.notificationStore.Insert(ctx, notification)
 	if err != nil {
 		return errors.Wrapf(err, "creating notification %+v", notification)
@@ -40,3 +132,324 @@ func (ha *App) CreateNotification(ctx context.Context, notification *model.Notif
 
 	return nil
 }
+
+// CreateNotifications creates valid notifications in single transaction.
+// Validation error is returned for every invalid notification at the same position, invalid notifications are skipped.
+func (ha *App) CreateNotifications(ctx context.Context, notifications []*model.Notification) ([]error, error) {
+	if len(notifications) > maxBulkSize {
+		return nil, ErrBulkTooLarge
+	}
+
+	errs := make([]error, len(notifications))
+	valid := make([]*model.Notification, 0, len(notifications))
+
+	for i, n := range notifications {
+		ha.setLanguage(n)
+		errs[i] = validateNotification(n)
+		if errs[i] == nil {
+			valid = append(valid, n)
+		}
+	}
+
+	if len(valid) == 0 {
+		return errs, nil
+	}
+
+	now := time.Now()
+	for _, n := range valid {
+		schedule(n, now)
+	}
+
+	err := ha.notificationStore.InsertBatch(ctx, valid)
+	if err != nil {
+		return nil, errors.Wrapf(err, "creating %d notifications", len(valid))
+	}
+
+	return errs, nil
+}
+
+// UpdateNotification applies patch to notification, patch is rejected with
+// dataprovider.ErrVersionConflict if notification was changed since patch's version.
+func (ha *App) UpdateNotification(ctx context.Context, id int, patch *model.NotificationPatch) (*model.Notification, error) {
+	notification, err := ha.notificationStore.Get(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting notification %d", id)
+	}
+
+	if notification.RevokedAt != nil {
+		return nil, dataprovider.ErrNotFound
+	}
+
+	if notification.Version != patch.Version {
+		return nil, dataprovider.ErrVersionConflict
+	}
+
+	if patch.Title != nil {
+		notification.Title = *patch.Title
+	}
+	if patch.Body != nil {
+		notification.Body = *patch.Body
+	}
+	if patch.Type != nil {
+		notification.Type = *patch.Type
+	}
+	if patch.Priority != nil {
+		notification.Priority = *patch.Priority
+	}
+	if patch.FromTime.Set {
+		notification.FromTime = patch.FromTime.Time
+	}
+	if patch.TillTime.Set {
+		notification.TillTime = patch.TillTime.Time
+	}
+
+	if err := validateNotification(notification); err != nil {
+		return nil, err
+	}
+
+	err = ha.notificationStore.Update(ctx, notification)
+	if err != nil {
+		return nil, errors.Wrapf(err, "updating notification %d", id)
+	}
+
+	return notification, nil
+}
+
+// RevokeNotification withdraws notification from users' inboxes.
+func (ha *App) RevokeNotification(ctx context.Context, id int) error {
+	err := ha.notificationStore.Revoke(ctx, id)
+	if err != nil {
+		return errors.Wrapf(err, "revoking notification %d", id)
+	}
+
+	return nil
+}
+
+// GetUserNotifications returns page of user's notifications, global notifications are included.
+// Notifications are localized according to user's preferred locales and fallback locales.
+// Cursor pointing to the next page is returned, it is nil for the last page.
+func (ha *App) GetUserNotifications(
+	ctx context.Context,
+	user *model.User,
+	filter *model.InboxFilter,
+) ([]*model.Notification, *model.NotificationCursor, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultPageSize
+	}
+	if filter.Limit > maxPageSize {
+		filter.Limit = maxPageSize
+	}
+
+	for _, p := range filter.Priorities {
+		if !p.Valid() {
+			return nil, nil, ErrInvalidPriority
+		}
+	}
+
+	user, err := ha.inboxUser(ctx, user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pageSize := filter.Limit
+	// Request one extra notification to find out whether next page exists
+	filter.Limit++
+
+	notifications, err := ha.notificationStore.GetByUser(ctx, user, filter)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "getting notifications for user %d", user.ID)
+	}
+
+	locales := ha.locales(user)
+	for _, n := range notifications {
+		localize(n, locales)
+	}
+
+	if len(notifications) <= pageSize {
+		return notifications, nil, nil
+	}
+
+	notifications = notifications[:pageSize]
+	last := notifications[pageSize-1]
+
+	return notifications, &model.NotificationCursor{
+		Priority:  last.Priority,
+		CreatedAt: last.CreatedAt,
+		ID:        last.ID,
+	}, nil
+}
+
+// GetPublishedAfter returns up to limit user's notifications published after position,
+// notifications are localized according to user's preferred locales.
+func (ha *App) GetPublishedAfter(
+	ctx context.Context,
+	user *model.User,
+	pos *model.StreamPosition,
+	limit int,
+) ([]*model.Notification, error) {
+	user, err := ha.inboxUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	notifications, err := ha.notificationStore.GetPublishedAfter(ctx, user, pos, limit)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting notifications published after %+v", pos)
+	}
+
+	for _, n := range notifications {
+		localize(n, ha.locales(user))
+	}
+
+	return notifications, nil
+}
+
+// Localize returns copy of notification localized for the user.
+func (ha *App) Localize(user *model.User, notification *model.Notification) *model.Notification {
+	n := *notification
+	localize(&n, ha.locales(user))
+
+	return &n
+}
+
+func (ha *App) locales(user *model.User) []string {
+	return append(append([]string{}, user.Locales...), ha.fallbackLocales...)
+}
+
+// ListNotifications returns page of notifications matching filter, it is intended for administrative usage.
+// Cursor pointing to the next page is returned, it is nil for the last page.
+func (ha *App) ListNotifications(
+	ctx context.Context,
+	filter *model.NotificationFilter,
+) ([]*model.Notification, *model.NotificationCursor, error) {
+	switch filter.State {
+	case "",
+		model.NotificationStateActive,
+		model.NotificationStateExpired,
+		model.NotificationStatePending,
+		model.NotificationStateRevoked,
+		model.NotificationStateScheduled:
+	default:
+		return nil, nil, ErrInvalidState
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultPageSize
+	}
+	if filter.Limit > maxPageSize {
+		filter.Limit = maxPageSize
+	}
+
+	pageSize := filter.Limit
+	// Request one extra notification to find out whether next page exists
+	filter.Limit++
+
+	notifications, err := ha.notificationStore.Find(ctx, filter)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "finding notifications by filter %+v", filter)
+	}
+
+	if len(notifications) <= pageSize {
+		return notifications, nil, nil
+	}
+
+	notifications = notifications[:pageSize]
+	last := notifications[pageSize-1]
+
+	return notifications, &model.NotificationCursor{
+		Priority:  last.Priority,
+		CreatedAt: last.CreatedAt,
+		ID:        last.ID,
+	}, nil
+}
+
+// MarkRead marks notification as read by the user,
+// dataprovider.ErrNotFound is returned if notification is not visible to the user.
+func (ha *App) MarkRead(ctx context.Context, user *model.User, id int) error {
+	user, err := ha.inboxUser(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	err = ha.notificationStore.MarkRead(ctx, user, id)
+	if err != nil {
+		return errors.Wrapf(err, "marking notification %d as read", id)
+	}
+
+	return nil
+}
+
+// MarkAllRead marks all user's notifications as read.
+func (ha *App) MarkAllRead(ctx context.Context, user *model.User) error {
+	user, err := ha.inboxUser(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	err = ha.notificationStore.MarkAllRead(ctx, user)
+	if err != nil {
+		return errors.Wrapf(err, "marking all notifications as read for user %d", user.ID)
+	}
+
+	return nil
+}
+
+// CountUnread returns number of user's notifications that are not read yet.
+func (ha *App) CountUnread(ctx context.Context, user *model.User) (int, error) {
+	user, err := ha.inboxUser(ctx, user)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := ha.notificationStore.CountUnread(ctx, user)
+	if err != nil {
+		return 0, errors.Wrapf(err, "counting unread notifications for user %d", user.ID)
+	}
+
+	return count, nil
+}
+
+// validateNotification checks notification's content and normalizes locales of its translations.
+// Notification without priority gets normal priority.
+func validateNotification(notification *model.Notification) error {
+	if notification.Title == "" || notification.Body == "" || notification.Type == "" {
+		return ErrMissingContent
+	}
+
+	if notification.Priority == "" {
+		notification.Priority = model.PriorityNormal
+	}
+
+	if !notification.Priority.Valid() {
+		return ErrInvalidPriority
+	}
+
+	if notification.FromTime != nil && notification.TillTime != nil &&
+		!notification.FromTime.Before(*notification.TillTime) {
+		return ErrInvalidVisibilityWindow
+	}
+
+	if len(notification.CollapseKey) > maxCollapseKeyLength {
+		return ErrInvalidCollapseKey
+	}
+
+	if _, ok := searchLanguages[notification.Language]; !ok && notification.Language != "" {
+		return ErrInvalidLanguage
+	}
+
+	return normalizeTranslations(notification)
+}
+
+// setLanguage sets configured search language to notification without language.
+func (ha *App) setLanguage(notification *model.Notification) {
+	if notification.Language == "" {
+		notification.Language = ha.searchLanguage
+	}
+}
+
+// schedule marks notification as published at now unless it is scheduled to the future.
+func schedule(notification *model.Notification, now time.Time) {
+	if notification.PublishAt == nil || !notification.PublishAt.After(now) {
+		notification.PublishedAt = &now
+	}
+}
@@ -0,0 +1,177 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/dataprovider"
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+// notificationStoreMock keeps notifications ordered the way administrative listing is.
+type notificationStoreMock struct {
+	dataprovider.NotificationStore
+
+	notifications []*model.Notification
+	// limits are limits of Find calls
+	limits []int
+}
+
+func (s *notificationStoreMock) Find(ctx context.Context, filter *model.NotificationFilter) ([]*model.Notification, error) {
+	s.limits = append(s.limits, filter.Limit)
+
+	page := make([]*model.Notification, 0, filter.Limit)
+	for _, n := range s.notifications {
+		if filter.After != nil && !n.CreatedAt.Before(filter.After.CreatedAt) {
+			continue
+		}
+
+		if len(page) == filter.Limit {
+			break
+		}
+
+		page = append(page, n)
+	}
+
+	return page, nil
+}
+
+func (s *notificationStoreMock) Get(ctx context.Context, id int) (*model.Notification, error) {
+	for _, n := range s.notifications {
+		if n.ID == id {
+			c := *n
+			return &c, nil
+		}
+	}
+
+	return nil, dataprovider.ErrNotFound
+}
+
+func (s *notificationStoreMock) Update(ctx context.Context, notification *model.Notification) error {
+	notification.Version++
+	return nil
+}
+
+func TestListNotifications(t *testing.T) {
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	store := &notificationStoreMock{}
+	for i := 45; i > 0; i-- {
+		store.notifications = append(store.notifications, &model.Notification{
+			ID:        i,
+			Priority:  model.PriorityNormal,
+			CreatedAt: created.Add(time.Duration(i) * time.Minute),
+		})
+	}
+
+	app := &App{notificationStore: store}
+
+	var (
+		pages  []int
+		cursor *model.NotificationCursor
+	)
+
+	for {
+		notifications, next, err := app.ListNotifications(context.Background(), &model.NotificationFilter{After: cursor})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pages = append(pages, len(notifications))
+
+		if next == nil {
+			break
+		}
+
+		last := notifications[len(notifications)-1]
+		if next.ID != last.ID || !next.CreatedAt.Equal(last.CreatedAt) {
+			t.Fatalf("expected cursor pointing to notification %d, got %+v", last.ID, next)
+		}
+
+		cursor = next
+	}
+
+	if len(pages) != 3 || pages[0] != defaultPageSize || pages[1] != defaultPageSize || pages[2] != 5 {
+		t.Fatalf("unexpected page sizes %v", pages)
+	}
+
+	_, _, err := app.ListNotifications(context.Background(), &model.NotificationFilter{Limit: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if limit := store.limits[len(store.limits)-1]; limit != maxPageSize+1 {
+		t.Fatalf("expected page size capped to %d, got store limit %d", maxPageSize, limit)
+	}
+}
+
+func TestListNotificationsInvalidState(t *testing.T) {
+	app := &App{notificationStore: &notificationStoreMock{}}
+
+	_, _, err := app.ListNotifications(context.Background(), &model.NotificationFilter{State: "archived"})
+	if errors.Cause(err) != ErrInvalidState {
+		t.Fatalf("expected %v, got %v", ErrInvalidState, err)
+	}
+}
+
+func TestUpdateNotificationWindow(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	till := from.Add(time.Hour)
+	later := till.Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		patch    model.NotificationPatch
+		fromTime *time.Time
+		tillTime *time.Time
+	}{
+		{"window is left unchanged", model.NotificationPatch{}, &from, &till},
+		{"till time is changed", model.NotificationPatch{TillTime: model.TimePatch{Set: true, Time: &later}}, &from, &later},
+		{"from time is cleared", model.NotificationPatch{FromTime: model.TimePatch{Set: true}}, nil, &till},
+		{
+			"window is cleared",
+			model.NotificationPatch{FromTime: model.TimePatch{Set: true}, TillTime: model.TimePatch{Set: true}},
+			nil,
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &notificationStoreMock{
+				notifications: []*model.Notification{{
+					ID:       1,
+					Title:    "Title",
+					Body:     "Body",
+					Type:     "news",
+					FromTime: &from,
+					TillTime: &till,
+					Version:  3,
+				}},
+			}
+			app := &App{notificationStore: store}
+
+			tt.patch.Version = 3
+
+			n, err := app.UpdateNotification(context.Background(), 1, &tt.patch)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !equalTime(n.FromTime, tt.fromTime) || !equalTime(n.TillTime, tt.tillTime) {
+				t.Fatalf("expected window %v - %v, got %v - %v", tt.fromTime, tt.tillTime, n.FromTime, n.TillTime)
+			}
+		})
+	}
+}
+
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Equal(*b)
+}
@@ -0,0 +1,310 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"go.uber.org/fx"
+
+	"github.com/hummerd/gophercon/internal/config"
+	"github.com/hummerd/gophercon/internal/dataprovider"
+	"github.com/hummerd/gophercon/internal/model"
+	"github.com/hummerd/gophercon/internal/service"
+)
+
+var (
+	// errUnknownUser is recorded for deliveries to users unknown to users service.
+	errUnknownUser = errors.Wrap(service.ErrUndeliverable, "unknown user")
+	// errRevoked is recorded for deliveries of notifications revoked before they were sent.
+	errRevoked = errors.Wrap(service.ErrUndeliverable, "notification is revoked")
+)
+
+// ListNotificationDeliveries returns deliveries of notification through all channels.
+func (ha *App) ListNotificationDeliveries(ctx context.Context, id int) ([]*model.Delivery, error) {
+	deliveries, err := ha.deliveryStore.ListByNotification(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing deliveries of notification %d", id)
+	}
+
+	return deliveries, nil
+}
+
+// NewChannelDeliverer creates ChannelDeliverer sending due deliveries and digests every channel interval.
+func NewChannelDeliverer(
+	lc fx.Lifecycle,
+	cfg *config.Config,
+	deliveryStore dataprovider.DeliveryStore,
+	notificationStore dataprovider.NotificationStore,
+	preferenceStore dataprovider.PreferenceStore,
+	digestStore dataprovider.DigestStore,
+	templateStore dataprovider.TemplateStore,
+	userStore service.UserStore,
+	channels service.Channels,
+) *ChannelDeliverer {
+	d := &ChannelDeliverer{
+		deliveryStore:     deliveryStore,
+		notificationStore: notificationStore,
+		preferenceStore:   preferenceStore,
+		digestStore:       digestStore,
+		userStore:         userStore,
+		channels:          make(map[string]service.Channel, len(channels)),
+		types: map[string][]string{
+			model.ChannelEmail: cfg.EmailTypes,
+			model.ChannelPush:  cfg.PushTypes,
+		},
+		mandatoryTypes:  cfg.MandatoryTypes,
+		fallbackLocales: cfg.FallbackLocales,
+		batchSize:       cfg.ChannelBatchSize,
+		maxAttempts:     cfg.ChannelMaxAttempts,
+		retryBase:       cfg.ChannelRetryBase,
+		retryMax:        cfg.ChannelRetryMax,
+		digestAt:        digestAt(cfg),
+		digests: digestBuilder{
+			digestStore:       digestStore,
+			notificationStore: notificationStore,
+			templateStore:     templateStore,
+		},
+	}
+
+	for _, c := range channels {
+		d.channels[c.Name()] = c
+	}
+
+	appendTicker(lc, "channel deliverer", cfg.ChannelInterval, func(ctx context.Context) {
+		processBatches(ctx, d.batchSize, "can not claim due deliveries", d.deliverDue)
+		processBatches(ctx, d.batchSize, "can not claim due digests", d.sendDigests)
+	})
+
+	return d
+}
+
+// ChannelDeliverer enqueues deliveries of published notifications through channels configured
+// for notification's type and enabled by user's preferences, and sends them.
+// Critical notifications are delivered regardless of preferences.
+// Broadcast notifications are not delivered through channels.
+// Non-critical deliveries falling into user's quiet hours are deferred until quiet hours end.
+// Deliveries through user's digest channel are collected and sent within periodic digest.
+// Failed deliveries are retried with exponential backoff until attempts are exhausted.
+type ChannelDeliverer struct {
+	deliveryStore     dataprovider.DeliveryStore
+	notificationStore dataprovider.NotificationStore
+	preferenceStore   dataprovider.PreferenceStore
+	digestStore       dataprovider.DigestStore
+	userStore         service.UserStore
+
+	digests digestBuilder
+
+	channels map[string]service.Channel
+	// types are notification types delivered through channel
+	types map[string][]string
+	// mandatoryTypes are delivered regardless of user's preferences
+	mandatoryTypes []string
+
+	fallbackLocales []string
+	batchSize       int
+	maxAttempts     int
+	retryBase       time.Duration
+	retryMax        time.Duration
+	// digestAt is a local time of daily and weekly digests in minutes since midnight
+	digestAt int
+}
+
+// Publish implements service.EventPublisher, it enqueues deliveries of published notification.
+// Deliveries matching user's digest are collected to digest.
+func (d *ChannelDeliverer) Publish(ctx context.Context, event *model.Event) error {
+	n := event.Notification
+	if event.Type != model.EventNotificationPublished || n.UserID == nil {
+		return nil
+	}
+
+	preferences, err := d.preferenceStore.ListByUser(ctx, *n.UserID)
+	if err != nil {
+		return errors.Wrapf(err, "listing preferences of user %d", *n.UserID)
+	}
+
+	digest, err := d.digestStore.Get(ctx, *n.UserID)
+	if err != nil {
+		return errors.Wrapf(err, "getting digest of user %d", *n.UserID)
+	}
+
+	now := time.Now()
+	deliveries := make([]*model.Delivery, 0, len(d.channels))
+
+	for name := range d.channels {
+		if !d.routed(name, n.Type) {
+			continue
+		}
+
+		if !n.Priority.BypassesMutes() && !enabled(preferences, d.mandatoryTypes, n.Type, name) {
+			continue
+		}
+
+		delivery := &model.Delivery{
+			NotificationID: n.ID,
+			UserID:         *n.UserID,
+			Channel:        name,
+			Status:         model.DeliveryPending,
+			NextAttemptAt:  &now,
+		}
+
+		if digest != nil && digest.Channel == name && n.Priority.AtMost(digest.MaxPriority) {
+			delivery.Status = model.DeliveryCollected
+			delivery.NextAttemptAt = nil
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	err = d.deliveryStore.Insert(ctx, deliveries)
+	if err != nil {
+		return errors.Wrapf(err, "enqueuing deliveries of notification %d", n.ID)
+	}
+
+	return nil
+}
+
+// routed reports whether notifications of type are delivered through channel.
+func (d *ChannelDeliverer) routed(channel, notificationType string) bool {
+	for _, t := range d.types[channel] {
+		if t == notificationType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// deliverDue sends batch of due deliveries concurrently.
+func (d *ChannelDeliverer) deliverDue(ctx context.Context) (int, error) {
+	deliveries, err := d.deliveryStore.ClaimDue(ctx, d.batchSize, deliveryLease)
+	if err != nil {
+		return 0, err
+	}
+
+	inParallel(len(deliveries), func(i int) {
+		d.deliver(ctx, deliveries[i])
+	})
+
+	return len(deliveries), nil
+}
+
+// deliver makes single attempt of delivery and records its result.
+func (d *ChannelDeliverer) deliver(ctx context.Context, delivery *model.Delivery) {
+	logger := log.With().
+		Int("notification_id", delivery.NotificationID).
+		Int64("user_id", delivery.UserID).
+		Str("channel", delivery.Channel).
+		Logger()
+
+	err := d.send(ctx, delivery)
+	if ctx.Err() != nil {
+		return
+	}
+
+	if deferred, ok := err.(*deferral); ok {
+		// Deferral is not an attempt
+		delivery.NextAttemptAt = &deferred.until
+		logger.Debug().Time("until", deferred.until).Msg("delivery is deferred by quiet hours")
+
+		err = d.deliveryStore.Update(ctx, delivery)
+		if err != nil {
+			logger.Error().Err(err).Msg("can not defer delivery")
+		}
+		return
+	}
+
+	delivery.Attempts++
+	delivery.NextAttemptAt = nil
+	delivery.Error = ""
+
+	switch {
+	case err == nil:
+		now := time.Now()
+		delivery.Status = model.DeliverySucceeded
+		delivery.SentAt = &now
+	case errors.Cause(err) != service.ErrUndeliverable && delivery.Attempts < d.maxAttempts:
+		delivery.Error = err.Error()
+		next := time.Now().Add(backoff(d.retryBase, d.retryMax, delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	default:
+		delivery.Error = err.Error()
+		delivery.Status = model.DeliveryFailed
+	}
+
+	if err != nil {
+		logger.Warn().Err(err).Int("attempts", delivery.Attempts).Msg("can not deliver notification")
+	}
+
+	err = d.deliveryStore.Update(ctx, delivery)
+	if err != nil {
+		logger.Error().Err(err).Msg("can not record delivery attempt")
+	}
+}
+
+func (d *ChannelDeliverer) send(ctx context.Context, delivery *model.Delivery) error {
+	channel, ok := d.channels[delivery.Channel]
+	if !ok {
+		return errors.Wrapf(service.ErrUndeliverable, "unknown channel %s", delivery.Channel)
+	}
+
+	n, err := d.notificationStore.Get(ctx, delivery.NotificationID)
+	if err != nil {
+		return errors.Wrapf(err, "getting notification %d", delivery.NotificationID)
+	}
+
+	if n.RevokedAt != nil {
+		return errRevoked
+	}
+
+	if !n.Priority.BypassesMutes() {
+		until, err := d.quietUntil(ctx, delivery.UserID, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if until != nil {
+			return &deferral{until: *until}
+		}
+	}
+
+	user, err := d.userStore.GetUser(ctx, delivery.UserID)
+	if err != nil {
+		return err
+	}
+
+	if user == nil {
+		return errUnknownUser
+	}
+
+	localize(n, append(append([]string{}, user.Locales...), d.fallbackLocales...))
+
+	return channel.Send(ctx, user, n)
+}
+
+// quietUntil returns end of user's quiet hours if t falls into them.
+func (d *ChannelDeliverer) quietUntil(ctx context.Context, userID int64, t time.Time) (*time.Time, error) {
+	quietHours, err := d.preferenceStore.GetQuietHours(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting quiet hours of user %d", userID)
+	}
+
+	if quietHours == nil {
+		return nil, nil
+	}
+
+	w, err := parseQuietHours(quietHours)
+	if err != nil {
+		// Stored quiet hours are validated, but time zone database may differ between instances
+		log.Warn().Err(err).Int64("user_id", userID).Msg("quiet hours are ignored")
+		return nil, nil
+	}
+
+	until, ok := w.until(t)
+	if !ok {
+		return nil, nil
+	}
+
+	return &until, nil
+}
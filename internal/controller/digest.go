@@ -0,0 +1,377 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/hummerd/gophercon/internal/config"
+	"github.com/hummerd/gophercon/internal/dataprovider"
+	"github.com/hummerd/gophercon/internal/model"
+	"github.com/hummerd/gophercon/internal/service"
+)
+
+const (
+	// maxDigestItems limits number of notifications sent within single digest, the rest goes to the next one
+	maxDigestItems = 100
+	// digestListed is a number of notifications listed in digest body
+	digestListed = 20
+
+	defaultDigestTitle = "{{.count}} new notifications"
+	defaultDigestBody  = "{{.items}}"
+
+	// defaultDigestAt is a local time of daily and weekly digests in minutes since midnight
+	defaultDigestAt = 9 * 60
+)
+
+// ErrInvalidDigest is returned when digest has unknown frequency, channel or priority.
+var ErrInvalidDigest = errors.New("digest must have known frequency and channel and non-critical max priority")
+
+// digestChannels are channels digests are sent through
+var digestChannels = map[string]struct{}{
+	model.ChannelEmail: {},
+	model.ChannelPush:  {},
+}
+
+// SetDigest validates and stores user's digest, the next digest is scheduled from now.
+// Digest collects low priority notifications unless MaxPriority is set, timezone is UTC by default.
+func (ha *App) SetDigest(ctx context.Context, user *model.User, digest *model.Digest) (*model.Preferences, error) {
+	if digest.MaxPriority == "" {
+		digest.MaxPriority = model.PriorityLow
+	}
+
+	if digest.Timezone == "" {
+		digest.Timezone = "UTC"
+	}
+
+	if _, ok := digestChannels[digest.Channel]; !ok ||
+		!digest.MaxPriority.Valid() || digest.MaxPriority.BypassesMutes() {
+		return nil, ErrInvalidDigest
+	}
+
+	next, err := nextDigest(digest, ha.digestAt, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	digest.UserID = user.ID
+	digest.NextAt = next
+
+	err = ha.digestStore.Set(ctx, digest)
+	if err != nil {
+		return nil, errors.Wrapf(err, "setting digest of user %d", user.ID)
+	}
+
+	return ha.GetPreferences(ctx, user)
+}
+
+// DeleteDigest turns user's digest off, collected notifications are delivered one by one.
+func (ha *App) DeleteDigest(ctx context.Context, user *model.User) error {
+	err := ha.digestStore.Delete(ctx, user.ID)
+	if err != nil {
+		return errors.Wrapf(err, "deleting digest of user %d", user.ID)
+	}
+
+	return nil
+}
+
+// PreviewDigest returns user's next digest rendered from notifications collected so far.
+func (ha *App) PreviewDigest(ctx context.Context, user *model.User) (*model.DigestPreview, error) {
+	digest, err := ha.digestStore.Get(ctx, user.ID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting digest of user %d", user.ID)
+	}
+
+	if digest == nil {
+		return nil, dataprovider.ErrNotFound
+	}
+
+	b := &digestBuilder{
+		digestStore:       ha.digestStore,
+		notificationStore: ha.notificationStore,
+		templateStore:     ha.templateStore,
+	}
+
+	content, err := b.build(ctx, digest, append(append([]string{}, user.Locales...), ha.fallbackLocales...))
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.DigestPreview{
+		Digest:       digest,
+		Notification: content.notification,
+		Items:        content.items,
+	}, nil
+}
+
+// digestBuilder renders digest from deliveries collected to it.
+type digestBuilder struct {
+	digestStore       dataprovider.DigestStore
+	notificationStore dataprovider.NotificationStore
+	templateStore     dataprovider.TemplateStore
+}
+
+// digestContent is a rendered digest along with its deliveries.
+type digestContent struct {
+	// notification is a summary, it is nil when there are no items
+	notification *model.Notification
+	// items are localized notifications of included deliveries
+	items    []*model.Notification
+	included []*model.Delivery
+	// revoked are deliveries of notifications revoked after they were collected
+	revoked []*model.Delivery
+}
+
+func (b *digestBuilder) build(ctx context.Context, digest *model.Digest, locales []string) (*digestContent, error) {
+	deliveries, err := b.digestStore.ListCollected(ctx, digest.UserID, digest.Channel, maxDigestItems)
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing collected deliveries of user %d", digest.UserID)
+	}
+
+	content := &digestContent{
+		items:    make([]*model.Notification, 0, len(deliveries)),
+		included: make([]*model.Delivery, 0, len(deliveries)),
+	}
+
+	for _, delivery := range deliveries {
+		n, err := b.notificationStore.Get(ctx, delivery.NotificationID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting notification %d", delivery.NotificationID)
+		}
+
+		if n.RevokedAt != nil {
+			content.revoked = append(content.revoked, delivery)
+			continue
+		}
+
+		localize(n, locales)
+
+		content.items = append(content.items, n)
+		content.included = append(content.included, delivery)
+	}
+
+	if len(content.items) == 0 {
+		return content, nil
+	}
+
+	content.notification, err = b.render(ctx, digest, content.items)
+	if err != nil {
+		return nil, err
+	}
+
+	return content, nil
+}
+
+// render renders summary of items with digest template, built-in template is used when there is none.
+func (b *digestBuilder) render(ctx context.Context, digest *model.Digest, items []*model.Notification) (*model.Notification, error) {
+	tmpl, err := b.templateStore.Get(ctx, model.DigestType)
+	if err != nil && errors.Cause(err) != dataprovider.ErrNotFound {
+		return nil, errors.Wrapf(err, "getting template %q", model.DigestType)
+	}
+
+	if tmpl == nil {
+		tmpl = &model.Template{Title: defaultDigestTitle, Body: defaultDigestBody}
+	}
+
+	list := &strings.Builder{}
+	for i, n := range items {
+		if i == digestListed {
+			fmt.Fprintf(list, "and %d more\n", len(items)-digestListed)
+			break
+		}
+
+		fmt.Fprintf(list, "- %s\n", n.Title)
+	}
+
+	vars := map[string]string{
+		"count":     strconv.Itoa(len(items)),
+		"items":     strings.TrimSuffix(list.String(), "\n"),
+		"frequency": digest.Frequency,
+	}
+
+	title, err := render(tmpl.Title, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := render(tmpl.Body, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Notification{
+		UserID:    &digest.UserID,
+		Type:      model.DigestType,
+		Title:     title,
+		Body:      body,
+		Priority:  model.PriorityLow,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// sendDigests sends batch of due digests.
+func (d *ChannelDeliverer) sendDigests(ctx context.Context) (int, error) {
+	digests, err := d.digestStore.ClaimDue(ctx, d.batchSize, deliveryLease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, digest := range digests {
+		d.sendDigest(ctx, digest)
+	}
+
+	return len(digests), nil
+}
+
+// sendDigest makes single attempt to send digest and schedules the next one,
+// failed digest is retried after retryBase.
+func (d *ChannelDeliverer) sendDigest(ctx context.Context, digest *model.Digest) {
+	logger := log.With().
+		Int64("user_id", digest.UserID).
+		Str("channel", digest.Channel).
+		Logger()
+
+	next, err := d.digest(ctx, digest)
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err != nil {
+		logger.Warn().Err(err).Msg("can not send digest")
+		next = time.Now().Add(d.retryBase)
+	}
+
+	err = d.digestStore.Reschedule(ctx, digest.UserID, next)
+	if err != nil && errors.Cause(err) != dataprovider.ErrNotFound {
+		logger.Error().Err(err).Msg("can not schedule next digest")
+	}
+}
+
+// digest sends collected deliveries within digest and returns time of the next digest.
+// Digest falling into user's quiet hours is postponed until they end.
+func (d *ChannelDeliverer) digest(ctx context.Context, digest *model.Digest) (time.Time, error) {
+	now := time.Now()
+
+	until, err := d.quietUntil(ctx, digest.UserID, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if until != nil {
+		return *until, nil
+	}
+
+	next, err := nextDigest(digest, d.digestAt, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	channel, ok := d.channels[digest.Channel]
+	if !ok {
+		return time.Time{}, errors.Errorf("unknown channel %s", digest.Channel)
+	}
+
+	user, err := d.userStore.GetUser(ctx, digest.UserID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if user == nil {
+		return time.Time{}, errUnknownUser
+	}
+
+	content, err := d.digests.build(ctx, digest, append(append([]string{}, user.Locales...), d.fallbackLocales...))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	d.fail(ctx, content.revoked, errRevoked)
+
+	if content.notification == nil {
+		return next, nil
+	}
+
+	err = channel.Send(ctx, user, content.notification)
+	if errors.Cause(err) == service.ErrUndeliverable {
+		d.fail(ctx, content.included, err)
+		return next, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	ids := make([]int, 0, len(content.included))
+	for _, delivery := range content.included {
+		ids = append(ids, delivery.ID)
+	}
+
+	err = d.digestStore.MarkDigested(ctx, ids, time.Now())
+	if err != nil {
+		// Digest is sent again
+		return time.Time{}, errors.Wrap(err, "marking digested deliveries")
+	}
+
+	return next, nil
+}
+
+// fail records collected deliveries as failed with err.
+func (d *ChannelDeliverer) fail(ctx context.Context, deliveries []*model.Delivery, err error) {
+	for _, delivery := range deliveries {
+		delivery.Status = model.DeliveryFailed
+		delivery.Error = err.Error()
+		delivery.NextAttemptAt = nil
+
+		uerr := d.deliveryStore.Update(ctx, delivery)
+		if uerr != nil {
+			log.Error().Err(uerr).Int("delivery_id", delivery.ID).Msg("can not record delivery failure")
+		}
+	}
+}
+
+// nextDigest returns time of digest following t. Hourly digests are sent at the start of hour,
+// daily ones at local time at, weekly ones on Monday at local time at.
+func nextDigest(digest *model.Digest, at int, t time.Time) (time.Time, error) {
+	loc, err := time.LoadLocation(digest.Timezone)
+	if err != nil {
+		return time.Time{}, errors.Wrap(ErrInvalidTimezone, err.Error())
+	}
+
+	local := t.In(loc)
+	y, m, d := local.Date()
+
+	switch digest.Frequency {
+	case model.DigestHourly:
+		return wallClock(loc, y, m, d, (local.Hour()+1)*60), nil
+	case model.DigestDaily:
+		next := wallClock(loc, y, m, d, at)
+		if !next.After(t) {
+			next = wallClock(loc, y, m, d+1, at)
+		}
+		return next, nil
+	case model.DigestWeekly:
+		d += (int(time.Monday) - int(local.Weekday()) + 7) % 7
+		next := wallClock(loc, y, m, d, at)
+		if !next.After(t) {
+			next = wallClock(loc, y, m, d+7, at)
+		}
+		return next, nil
+	default:
+		return time.Time{}, ErrInvalidDigest
+	}
+}
+
+// digestAt parses local time of daily and weekly digests from config.
+func digestAt(cfg *config.Config) int {
+	at, ok := parseClock(cfg.DigestTime)
+	if !ok {
+		log.Warn().Str("digest_time", cfg.DigestTime).Msg("invalid digest time, default is used")
+		return defaultDigestAt
+	}
+
+	return at
+}
@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+	"go.uber.org/fx"
+
+	"github.com/hummerd/gophercon/internal/config"
+	"github.com/hummerd/gophercon/internal/dataprovider"
+)
+
+// NewDispatcher creates Dispatcher publishing due notifications every dispatch interval.
+func NewDispatcher(
+	lc fx.Lifecycle,
+	cfg *config.Config,
+	notificationStore dataprovider.NotificationStore,
+) *Dispatcher {
+	d := &Dispatcher{
+		notificationStore: notificationStore,
+		batchSize:         cfg.DispatchBatchSize,
+	}
+
+	appendTicker(lc, "notifications dispatcher", cfg.DispatchInterval, func(ctx context.Context) {
+		processBatches(ctx, d.batchSize, "can not dispatch scheduled notifications", d.publishDue)
+	})
+
+	return d
+}
+
+// Dispatcher publishes scheduled notifications when their publishing time comes,
+// events of published notifications are relayed from outbox by OutboxRelay.
+type Dispatcher struct {
+	notificationStore dataprovider.NotificationStore
+
+	batchSize int
+}
+
+// publishDue publishes batch of due notifications.
+func (d *Dispatcher) publishDue(ctx context.Context) (int, error) {
+	n, err := d.notificationStore.PublishDue(ctx, d.batchSize)
+	if n > 0 {
+		log.Debug().Int("count", n).Msg("scheduled notifications published")
+	}
+
+	return n, err
+}
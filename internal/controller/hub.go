@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.uber.org/fx"
+
+	"github.com/hummerd/gophercon/internal/config"
+	"github.com/hummerd/gophercon/internal/dataprovider"
+	"github.com/hummerd/gophercon/internal/events"
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+const (
+	// maxReconnectDelay limits pause between listener reconnects
+	maxReconnectDelay = 30 * time.Second
+	// hubBackfillPage is a number of notifications requested at once after reconnect
+	hubBackfillPage = 100
+)
+
+// NewHub creates Hub listening to notifications published by all service instances.
+func NewHub(
+	lc fx.Lifecycle,
+	cfg *config.Config,
+	listener dataprovider.NotificationListener,
+	notificationStore dataprovider.NotificationStore,
+	bus *events.Bus,
+) *Hub {
+	h := &Hub{
+		listener:          listener,
+		notificationStore: notificationStore,
+		bus:               bus,
+		reconnect:         cfg.ListenReconnect,
+	}
+
+	appendWorker(lc, "notifications hub", h.Run)
+
+	return h
+}
+
+// Hub delivers notifications published by any service instance to subscribers of the local events bus.
+// Notifications arrive through database, so Hub does not depend on outbox.
+// Notifications published while database connection was lost are delivered after reconnect.
+type Hub struct {
+	listener          dataprovider.NotificationListener
+	notificationStore dataprovider.NotificationStore
+	bus               *events.Bus
+
+	reconnect time.Duration
+
+	// pos is a position of the latest delivered notification, it is used by Run only
+	pos *model.StreamPosition
+}
+
+// Run listens to published notifications until ctx is done, lost connection is reestablished.
+func (h *Hub) Run(ctx context.Context) {
+	delay := h.reconnect
+
+	ready := func() error {
+		delay = h.reconnect
+		return h.backfill(ctx)
+	}
+
+	published := func(id int) error {
+		return h.deliver(ctx, id)
+	}
+
+	for {
+		err := h.listener.Listen(ctx, ready, published)
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Error().Err(err).Dur("retry_in", delay).Msg("notifications listener is disconnected")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+// backfill delivers notifications published while listener was disconnected.
+func (h *Hub) backfill(ctx context.Context) error {
+	if h.pos == nil {
+		pos, err := h.notificationStore.LastStreamPosition(ctx)
+		if err != nil {
+			return err
+		}
+
+		h.pos = pos
+		return nil
+	}
+
+	for {
+		notifications, err := h.notificationStore.FindPublishedAfter(ctx, h.pos, hubBackfillPage)
+		if err != nil {
+			return err
+		}
+
+		for _, n := range notifications {
+			h.emit(ctx, n)
+			h.advance(n)
+		}
+
+		if len(notifications) < hubBackfillPage {
+			return nil
+		}
+	}
+}
+
+func (h *Hub) deliver(ctx context.Context, id int) error {
+	n, err := h.notificationStore.Get(ctx, id)
+	if err == dataprovider.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	h.emit(ctx, n)
+	h.advance(n)
+
+	return nil
+}
+
+func (h *Hub) emit(ctx context.Context, n *model.Notification) {
+	h.bus.Publish(ctx, &model.Event{
+		Type:         model.EventNotificationPublished,
+		Notification: n,
+		At:           time.Now(),
+	})
+}
+
+// advance moves position forward to notification if it was published later.
+func (h *Hub) advance(n *model.Notification) {
+	if n.PublishSeq == nil {
+		return
+	}
+
+	if *n.PublishSeq > h.pos.PublishSeq {
+		h.pos = &model.StreamPosition{PublishSeq: *n.PublishSeq}
+	}
+}
@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"go.uber.org/fx"
+
+	"github.com/hummerd/gophercon/internal/config"
+	"github.com/hummerd/gophercon/internal/dataprovider"
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+	// idempotencyPoll is a pause between checks of in-flight request with the same key
+	idempotencyPoll = 100 * time.Millisecond
+)
+
+var (
+	// ErrInvalidIdempotencyKey is returned when idempotency key is empty or too long.
+	ErrInvalidIdempotencyKey = errors.New("idempotency key must be from 1 to 255 characters")
+	// ErrIdempotencyKeyReused is returned when idempotency key is reused for different request.
+	ErrIdempotencyKeyReused = errors.New("idempotency key is already used for different request")
+	// ErrIdempotencyKeyInFlight is returned when request with the same idempotency key is still processed.
+	ErrIdempotencyKeyInFlight = errors.New("request with the same idempotency key is in progress")
+)
+
+// BeginIdempotent claims user's idempotency key for request identified by hash.
+// Stored key is returned if the same request was processed already, its response must be replayed.
+// Nil key means request has to be processed, caller must complete or release key afterwards.
+// Request with the same key that is still processed is awaited for limited time.
+func (ha *App) BeginIdempotent(ctx context.Context, userID int64, key, hash string) (*model.IdempotencyKey, error) {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return nil, ErrInvalidIdempotencyKey
+	}
+
+	deadline := time.Now().Add(ha.idempotencyWait)
+
+	for {
+		claimed, err := ha.idempotencyStore.Claim(ctx, &model.IdempotencyKey{
+			UserID:      userID,
+			Key:         key,
+			RequestHash: hash,
+			ExpiresAt:   time.Now().Add(ha.idempotencyLease),
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "claiming idempotency key")
+		}
+
+		if claimed {
+			return nil, nil
+		}
+
+		stored, err := ha.idempotencyStore.Get(ctx, userID, key)
+		if err != nil && err != dataprovider.ErrNotFound {
+			return nil, errors.Wrap(err, "getting idempotency key")
+		}
+
+		// Key expired or released meanwhile, claim it again
+		if err == dataprovider.ErrNotFound {
+			continue
+		}
+
+		if stored.RequestHash != hash {
+			return nil, ErrIdempotencyKeyReused
+		}
+
+		if stored.Status == model.IdempotencyCompleted {
+			return stored, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, ErrIdempotencyKeyInFlight
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(idempotencyPoll):
+		}
+	}
+}
+
+// CompleteIdempotent stores response of request claimed by BeginIdempotent,
+// response is replayed for requests with the same key until key expires.
+func (ha *App) CompleteIdempotent(ctx context.Context, key *model.IdempotencyKey) error {
+	key.ExpiresAt = time.Now().Add(ha.idempotencyTTL)
+
+	err := ha.idempotencyStore.Complete(ctx, key)
+	if err != nil {
+		return errors.Wrapf(err, "completing idempotency key %q", key.Key)
+	}
+
+	return nil
+}
+
+// ReleaseIdempotent forgets key claimed by BeginIdempotent, so request with the key may be retried.
+func (ha *App) ReleaseIdempotent(ctx context.Context, userID int64, key string) error {
+	err := ha.idempotencyStore.Delete(ctx, userID, key)
+	if err != nil {
+		return errors.Wrapf(err, "releasing idempotency key %q", key)
+	}
+
+	return nil
+}
+
+// NewIdempotencyPurger creates IdempotencyPurger deleting expired keys every purge interval.
+func NewIdempotencyPurger(
+	lc fx.Lifecycle,
+	cfg *config.Config,
+	idempotencyStore dataprovider.IdempotencyStore,
+) *IdempotencyPurger {
+	p := &IdempotencyPurger{
+		idempotencyStore: idempotencyStore,
+	}
+
+	appendTicker(lc, "idempotency keys purger", cfg.IdempotencyPurgeInterval, p.purge)
+
+	return p
+}
+
+// IdempotencyPurger deletes expired idempotency keys.
+type IdempotencyPurger struct {
+	idempotencyStore dataprovider.IdempotencyStore
+}
+
+// purge deletes expired keys.
+func (p *IdempotencyPurger) purge(ctx context.Context) {
+	n, err := p.idempotencyStore.DeleteExpired(ctx)
+	if err != nil && ctx.Err() == nil {
+		log.Error().Err(err).Msg("can not delete expired idempotency keys")
+	}
+
+	if n > 0 {
+		log.Debug().Int("count", n).Msg("expired idempotency keys deleted")
+	}
+}
@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+// normalizeLocale converts locale to lower case BCP 47 like form, e.g. "en_US" to "en-us".
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(locale), "_", "-", -1))
+}
+
+// baseLocale returns language part of the locale, e.g. "en" for "en-us".
+func baseLocale(locale string) string {
+	if i := strings.IndexByte(locale, '-'); i > 0 {
+		return locale[:i]
+	}
+
+	return locale
+}
+
+func normalizeTranslations(notification *model.Notification) error {
+	if len(notification.Translations) == 0 {
+		return nil
+	}
+
+	translations := make(map[string]model.NotificationContent, len(notification.Translations))
+	for locale, c := range notification.Translations {
+		locale = normalizeLocale(locale)
+		if locale == "" || c.Title == "" || c.Body == "" {
+			return ErrInvalidTranslation
+		}
+		translations[locale] = c
+	}
+
+	notification.Translations = translations
+
+	return nil
+}
+
+// localize replaces notification's title and body with the best matching translation.
+// Locales are tried in order, exact match is preferred over language match.
+// Default title and body are kept if there is no matching translation.
+func localize(notification *model.Notification, locales []string) {
+	defer func() {
+		notification.Translations = nil
+	}()
+
+	if len(notification.Translations) == 0 {
+		return
+	}
+
+	for _, locale := range locales {
+		locale = normalizeLocale(locale)
+		if locale == "" {
+			continue
+		}
+
+		if c, ok := notification.Translations[locale]; ok {
+			setContent(notification, locale, c)
+			return
+		}
+
+		base := baseLocale(locale)
+		if c, ok := notification.Translations[base]; ok {
+			setContent(notification, base, c)
+			return
+		}
+
+		for _, l := range sortedLocales(notification.Translations) {
+			if baseLocale(l) == base {
+				setContent(notification, l, notification.Translations[l])
+				return
+			}
+		}
+	}
+}
+
+func setContent(notification *model.Notification, locale string, c model.NotificationContent) {
+	notification.Locale = locale
+	notification.Title = c.Title
+	notification.Body = c.Body
+}
+
+func sortedLocales(translations map[string]model.NotificationContent) []string {
+	locales := make([]string, 0, len(translations))
+	for l := range translations {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+
+	return locales
+}
@@ -0,0 +1,236 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog/log"
+	"go.uber.org/fx"
+
+	"github.com/hummerd/gophercon/internal/config"
+	"github.com/hummerd/gophercon/internal/dataprovider"
+	"github.com/hummerd/gophercon/internal/model"
+	"github.com/hummerd/gophercon/internal/service"
+)
+
+const (
+	defaultOutboxPage = 50
+	maxOutboxPage     = 500
+)
+
+// ErrInvalidOutboxStatus is returned when outbox entries are filtered by unknown status.
+var ErrInvalidOutboxStatus = errors.New("unknown outbox entry status")
+
+var (
+	outboxPending = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "notifications_outbox_pending",
+		Help: "Number of outbox entries waiting to be published",
+	})
+	outboxFailed = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "notifications_outbox_failed",
+		Help: "Number of outbox entries failed after all attempts",
+	})
+	outboxLag = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "notifications_outbox_lag_seconds",
+		Help: "Age of the oldest pending outbox entry",
+	})
+	outboxPublishLag = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "notifications_outbox_publish_lag_seconds",
+		Help:    "Time from storing outbox entry to its publishing",
+		Buckets: prometheus.ExponentialBuckets(0.05, 2, 14),
+	})
+	outboxPublished = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "notifications_outbox_published_total",
+		Help: "Number of published outbox entries",
+	})
+	outboxFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "notifications_outbox_failures_total",
+		Help: "Number of failed attempts to publish outbox entries",
+	})
+)
+
+// ListOutbox returns outbox entries matching filter from the latest.
+func (ha *App) ListOutbox(ctx context.Context, filter *model.OutboxFilter) ([]*model.OutboxEntry, error) {
+	switch filter.Status {
+	case "", model.DeliveryPending, model.DeliverySucceeded, model.DeliveryFailed:
+	default:
+		return nil, ErrInvalidOutboxStatus
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultOutboxPage
+	}
+	if filter.Limit > maxOutboxPage {
+		filter.Limit = maxOutboxPage
+	}
+
+	entries, err := ha.outboxStore.List(ctx, filter)
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing outbox entries by filter %+v", filter)
+	}
+
+	return entries, nil
+}
+
+// GetOutboxEntry returns outbox entry by id.
+func (ha *App) GetOutboxEntry(ctx context.Context, id int64) (*model.OutboxEntry, error) {
+	entry, err := ha.outboxStore.Get(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting outbox entry %d", id)
+	}
+
+	return entry, nil
+}
+
+// ReplayOutboxEntry publishes outbox entry again regardless of its status.
+func (ha *App) ReplayOutboxEntry(ctx context.Context, id int64) error {
+	err := ha.outboxStore.Replay(ctx, id)
+	if err != nil {
+		return errors.Wrapf(err, "replaying outbox entry %d", id)
+	}
+
+	return nil
+}
+
+// ReplayFailedOutbox publishes all failed outbox entries again, number of replayed entries is returned.
+func (ha *App) ReplayFailedOutbox(ctx context.Context) (int, error) {
+	n, err := ha.outboxStore.ReplayFailed(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "replaying failed outbox entries")
+	}
+
+	return n, nil
+}
+
+// NewOutboxRelay creates OutboxRelay publishing due entries and updating backlog metrics every outbox interval.
+func NewOutboxRelay(
+	lc fx.Lifecycle,
+	cfg *config.Config,
+	outboxStore dataprovider.OutboxStore,
+	notificationStore dataprovider.NotificationStore,
+	publisher service.EventPublisher,
+) *OutboxRelay {
+	r := &OutboxRelay{
+		outboxStore:       outboxStore,
+		notificationStore: notificationStore,
+		publisher:         publisher,
+		batchSize:         cfg.OutboxBatchSize,
+		maxAttempts:       cfg.OutboxMaxAttempts,
+		retryBase:         cfg.OutboxRetryBase,
+		retryMax:          cfg.OutboxRetryMax,
+	}
+
+	appendTicker(lc, "outbox relay", cfg.OutboxInterval, func(ctx context.Context) {
+		processBatches(ctx, r.batchSize, "can not claim due outbox entries", r.relayDue)
+		r.collectStats(ctx)
+	})
+
+	return r
+}
+
+// OutboxRelay publishes events stored to outbox at least once, so publishers must tolerate duplicates.
+// Entries are claimed in order of creation, failed entries are retried with exponential backoff
+// until attempts are exhausted.
+type OutboxRelay struct {
+	outboxStore       dataprovider.OutboxStore
+	notificationStore dataprovider.NotificationStore
+	publisher         service.EventPublisher
+
+	batchSize   int
+	maxAttempts int
+	retryBase   time.Duration
+	retryMax    time.Duration
+}
+
+// relayDue publishes batch of due entries in order of creation.
+func (r *OutboxRelay) relayDue(ctx context.Context) (int, error) {
+	entries, err := r.outboxStore.ClaimDue(ctx, r.batchSize, deliveryLease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, entry := range entries {
+		r.relay(ctx, entry)
+	}
+
+	return len(entries), nil
+}
+
+// relay makes single attempt to publish entry and records its result.
+func (r *OutboxRelay) relay(ctx context.Context, entry *model.OutboxEntry) {
+	logger := log.With().
+		Int64("outbox_id", entry.ID).
+		Int("notification_id", entry.NotificationID).
+		Logger()
+
+	err := r.publish(ctx, entry)
+	if ctx.Err() != nil {
+		return
+	}
+
+	entry.Attempts++
+	entry.NextAttemptAt = nil
+	entry.Error = ""
+
+	switch {
+	case err == nil:
+		now := time.Now()
+		entry.Status = model.DeliverySucceeded
+		entry.PublishedAt = &now
+		outboxPublished.Inc()
+		outboxPublishLag.Observe(now.Sub(entry.CreatedAt).Seconds())
+	case entry.Attempts < r.maxAttempts:
+		entry.Error = err.Error()
+		next := time.Now().Add(backoff(r.retryBase, r.retryMax, entry.Attempts))
+		entry.NextAttemptAt = &next
+	default:
+		entry.Error = err.Error()
+		entry.Status = model.DeliveryFailed
+	}
+
+	if err != nil {
+		outboxFailures.Inc()
+		logger.Warn().Err(err).Int("attempts", entry.Attempts).Msg("can not publish outbox entry")
+	}
+
+	err = r.outboxStore.Update(ctx, entry)
+	if err != nil {
+		logger.Error().Err(err).Msg("can not record outbox entry attempt")
+	}
+}
+
+func (r *OutboxRelay) publish(ctx context.Context, entry *model.OutboxEntry) error {
+	n, err := r.notificationStore.Get(ctx, entry.NotificationID)
+	if err != nil {
+		return errors.Wrapf(err, "getting notification %d", entry.NotificationID)
+	}
+
+	return r.publisher.Publish(ctx, &model.Event{
+		Type:         entry.Event,
+		Notification: n,
+		At:           entry.CreatedAt,
+	})
+}
+
+// collectStats updates outbox backlog metrics.
+func (r *OutboxRelay) collectStats(ctx context.Context) {
+	stats, err := r.outboxStore.Stats(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Error().Err(err).Msg("can not collect outbox stats")
+		}
+		return
+	}
+
+	outboxPending.Set(float64(stats.Pending))
+	outboxFailed.Set(float64(stats.Failed))
+
+	lag := 0.0
+	if stats.OldestPendingAt != nil {
+		lag = time.Since(*stats.OldestPendingAt).Seconds()
+	}
+	outboxLag.Set(lag)
+}
@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+const maxPreferences = 1000
+
+var (
+	// ErrInvalidPreference is returned when preference has no type or unknown channel.
+	ErrInvalidPreference = errors.New("preference must have type and known channel")
+	// ErrMandatoryType is returned when preference of mandatory notification type is set.
+	ErrMandatoryType = errors.New("preferences of mandatory notification type can not be changed")
+	// ErrTooManyPreferences is returned when too many preferences are set at once.
+	ErrTooManyPreferences = errors.New("too many preferences")
+)
+
+// preferenceChannels are channels preferences are set for
+var preferenceChannels = map[string]struct{}{
+	model.ChannelInbox: {},
+	model.ChannelEmail: {},
+	model.ChannelPush:  {},
+}
+
+// GetPreferences returns user's preferences, quiet hours, digest and mandatory notification types.
+func (ha *App) GetPreferences(ctx context.Context, user *model.User) (*model.Preferences, error) {
+	preferences, err := ha.preferenceStore.ListByUser(ctx, user.ID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing preferences of user %d", user.ID)
+	}
+
+	quietHours, err := ha.preferenceStore.GetQuietHours(ctx, user.ID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting quiet hours of user %d", user.ID)
+	}
+
+	digest, err := ha.digestStore.Get(ctx, user.ID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting digest of user %d", user.ID)
+	}
+
+	return &model.Preferences{
+		Preferences:    preferences,
+		QuietHours:     quietHours,
+		Digest:         digest,
+		MandatoryTypes: append([]string{}, ha.mandatoryTypes...),
+	}, nil
+}
+
+// SetPreferences replaces user's preferences, the latest preference wins when type and channel repeat.
+// Preferences of mandatory types are rejected.
+func (ha *App) SetPreferences(ctx context.Context, user *model.User, preferences []*model.Preference) (*model.Preferences, error) {
+	if len(preferences) > maxPreferences {
+		return nil, ErrTooManyPreferences
+	}
+
+	unique := make([]*model.Preference, 0, len(preferences))
+	index := make(map[model.Preference]int, len(preferences))
+
+	for _, p := range preferences {
+		if _, ok := preferenceChannels[p.Channel]; !ok || p.Type == "" {
+			return nil, ErrInvalidPreference
+		}
+
+		if contains(ha.mandatoryTypes, p.Type) {
+			return nil, errors.Wrap(ErrMandatoryType, p.Type)
+		}
+
+		key := model.Preference{Type: p.Type, Channel: p.Channel}
+		if i, ok := index[key]; ok {
+			unique[i] = p
+			continue
+		}
+
+		index[key] = len(unique)
+		unique = append(unique, p)
+	}
+
+	err := ha.preferenceStore.Replace(ctx, user.ID, unique)
+	if err != nil {
+		return nil, errors.Wrapf(err, "replacing preferences of user %d", user.ID)
+	}
+
+	return ha.GetPreferences(ctx, user)
+}
+
+// ApplyPreferences sets notification types user muted in inbox, muted notifications are not shown to user
+// unless their priority bypasses mutes.
+func (ha *App) ApplyPreferences(ctx context.Context, user *model.User) error {
+	preferences, err := ha.preferenceStore.ListByUser(ctx, user.ID)
+	if err != nil {
+		return errors.Wrapf(err, "listing preferences of user %d", user.ID)
+	}
+
+	muted := make([]string, 0)
+
+	for _, p := range preferences {
+		if !enabled(preferences, ha.mandatoryTypes, p.Type, model.ChannelInbox) && !contains(muted, p.Type) {
+			muted = append(muted, p.Type)
+		}
+	}
+
+	user.MutedTypes = muted
+
+	return nil
+}
+
+// inboxUser returns copy of user with preferences applied.
+func (ha *App) inboxUser(ctx context.Context, user *model.User) (*model.User, error) {
+	u := *user
+
+	err := ha.ApplyPreferences(ctx, &u)
+	if err != nil {
+		return nil, err
+	}
+
+	return &u, nil
+}
+
+// enabled reports whether notifications of type are received through channel,
+// type is enabled unless it is disabled by preference. Mandatory types are always enabled.
+func enabled(preferences []*model.Preference, mandatoryTypes []string, notificationType, channel string) bool {
+	if contains(mandatoryTypes, notificationType) {
+		return true
+	}
+
+	for _, p := range preferences {
+		if p.Type == notificationType && p.Channel == channel {
+			return p.Enabled
+		}
+	}
+
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
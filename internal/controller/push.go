@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/hummerd/gophercon/internal/dataprovider"
+	"github.com/hummerd/gophercon/internal/model"
+	"github.com/hummerd/gophercon/internal/service"
+)
+
+var (
+	// ErrInvalidDevice is returned when device has no token or unknown platform.
+	ErrInvalidDevice = errors.New("device must have token and known platform")
+	// errNoDevices is recorded for push deliveries to users without registered devices.
+	errNoDevices = errors.Wrap(service.ErrUndeliverable, "user has no devices")
+)
+
+// RegisterDevice registers user's device to receive push notifications.
+func (ha *App) RegisterDevice(ctx context.Context, user *model.User, device *model.Device) error {
+	if device.Token == "" || (device.Platform != model.PlatformFCM && device.Platform != model.PlatformAPNs) {
+		return ErrInvalidDevice
+	}
+
+	device.UserID = user.ID
+
+	err := ha.deviceStore.Register(ctx, device)
+	if err != nil {
+		return errors.Wrap(err, "registering device")
+	}
+
+	return nil
+}
+
+// UnregisterDevice stops push notifications to user's device.
+func (ha *App) UnregisterDevice(ctx context.Context, user *model.User, id int) error {
+	err := ha.deviceStore.Unregister(ctx, user.ID, id)
+	if err != nil {
+		return errors.Wrapf(err, "unregistering device %d", id)
+	}
+
+	return nil
+}
+
+// ListDevices returns user's devices.
+func (ha *App) ListDevices(ctx context.Context, user *model.User) ([]*model.Device, error) {
+	devices, err := ha.deviceStore.ListByUser(ctx, user.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing devices")
+	}
+
+	return devices, nil
+}
+
+// NewPushChannel creates push channel over configured providers.
+func NewPushChannel(deviceStore dataprovider.DeviceStore, providers service.PushProviders) *PushChannel {
+	c := &PushChannel{
+		deviceStore: deviceStore,
+		providers:   make(map[string]service.PushProvider, len(providers)),
+	}
+
+	for _, p := range providers {
+		c.providers[p.Platform()] = p
+	}
+
+	return c
+}
+
+// PushChannel implements service.Channel interface, it pushes notification to all user's devices.
+// Tokens rejected by providers are deleted from registry.
+type PushChannel struct {
+	deviceStore dataprovider.DeviceStore
+	providers   map[string]service.PushProvider
+}
+
+// Name implements service.Channel.
+func (c *PushChannel) Name() string {
+	return model.ChannelPush
+}
+
+// Send pushes notification to user's devices, delivery succeeds when any device receives it.
+func (c *PushChannel) Send(ctx context.Context, user *model.User, notification *model.Notification) error {
+	devices, err := c.deviceStore.ListByUser(ctx, user.ID)
+	if err != nil {
+		return errors.Wrapf(err, "listing devices of user %d", user.ID)
+	}
+
+	var (
+		sent    int
+		lastErr error
+		invalid = make(map[string][]string)
+	)
+
+	for _, d := range devices {
+		p, ok := c.providers[d.Platform]
+		if !ok {
+			continue
+		}
+
+		err := p.Push(ctx, d.Token, notification)
+		switch {
+		case err == nil:
+			sent++
+		case errors.Cause(err) == service.ErrInvalidToken:
+			invalid[d.Platform] = append(invalid[d.Platform], d.Token)
+		default:
+			lastErr = err
+		}
+	}
+
+	for platform, tokens := range invalid {
+		err := c.deviceStore.DeleteTokens(ctx, platform, tokens)
+		if err != nil {
+			zerolog.Ctx(ctx).Error().Err(err).Str("platform", platform).Msg("can not prune invalid device tokens")
+		}
+	}
+
+	switch {
+	case sent > 0:
+		return nil
+	case lastErr != nil:
+		return lastErr
+	default:
+		return errNoDevices
+	}
+}
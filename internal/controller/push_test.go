@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/dataprovider"
+	"github.com/hummerd/gophercon/internal/model"
+	"github.com/hummerd/gophercon/internal/service"
+)
+
+type deviceStoreMock struct {
+	dataprovider.DeviceStore
+
+	devices []*model.Device
+	deleted map[string][]string
+}
+
+func (s *deviceStoreMock) ListByUser(ctx context.Context, userID int64) ([]*model.Device, error) {
+	return s.devices, nil
+}
+
+func (s *deviceStoreMock) DeleteTokens(ctx context.Context, platform string, tokens []string) error {
+	if s.deleted == nil {
+		s.deleted = make(map[string][]string)
+	}
+
+	s.deleted[platform] = append(s.deleted[platform], tokens...)
+	return nil
+}
+
+// pushProviderMock fails pushes to tokens with errors from errs.
+type pushProviderMock struct {
+	platform string
+	errs     map[string]error
+}
+
+func (p *pushProviderMock) Platform() string {
+	return p.platform
+}
+
+func (p *pushProviderMock) Push(ctx context.Context, token string, notification *model.Notification) error {
+	if err, ok := p.errs[token]; ok {
+		return err
+	}
+
+	return nil
+}
+
+func TestPushChannelSend(t *testing.T) {
+	errUnavailable := errors.New("unavailable")
+	unregistered := errors.Wrap(service.ErrInvalidToken, "UNREGISTERED")
+	badDeviceToken := errors.Wrap(service.ErrInvalidToken, "BadDeviceToken")
+	gone := errors.Wrap(service.ErrInvalidToken, "Unregistered")
+
+	tests := []struct {
+		name    string
+		devices []*model.Device
+		errs    map[string]error
+		// err is an expected error cause
+		err     error
+		deleted map[string][]string
+	}{
+		{
+			name: "all devices",
+			devices: []*model.Device{
+				{Platform: model.PlatformFCM, Token: "fcm-1"},
+				{Platform: model.PlatformAPNs, Token: "apns-1"},
+			},
+		},
+		{
+			name: "invalid tokens are pruned",
+			devices: []*model.Device{
+				{Platform: model.PlatformFCM, Token: "fcm-1"},
+				{Platform: model.PlatformFCM, Token: "fcm-2"},
+				{Platform: model.PlatformAPNs, Token: "apns-1"},
+				{Platform: model.PlatformAPNs, Token: "apns-2"},
+			},
+			errs: map[string]error{
+				"fcm-2":  unregistered,
+				"apns-1": badDeviceToken,
+				"apns-2": gone,
+			},
+			deleted: map[string][]string{
+				model.PlatformFCM:  {"fcm-2"},
+				model.PlatformAPNs: {"apns-1", "apns-2"},
+			},
+		},
+		{
+			name: "all tokens are invalid",
+			devices: []*model.Device{
+				{Platform: model.PlatformFCM, Token: "fcm-1"},
+			},
+			errs:    map[string]error{"fcm-1": unregistered},
+			err:     service.ErrUndeliverable,
+			deleted: map[string][]string{model.PlatformFCM: {"fcm-1"}},
+		},
+		{
+			name: "temporary failure is retried",
+			devices: []*model.Device{
+				{Platform: model.PlatformFCM, Token: "fcm-1"},
+				{Platform: model.PlatformAPNs, Token: "apns-1"},
+			},
+			errs: map[string]error{
+				"fcm-1":  errUnavailable,
+				"apns-1": gone,
+			},
+			err:     errUnavailable,
+			deleted: map[string][]string{model.PlatformAPNs: {"apns-1"}},
+		},
+		{
+			name: "temporary failure of some devices",
+			devices: []*model.Device{
+				{Platform: model.PlatformFCM, Token: "fcm-1"},
+				{Platform: model.PlatformAPNs, Token: "apns-1"},
+			},
+			errs: map[string]error{"fcm-1": errUnavailable},
+		},
+		{
+			name:    "no devices",
+			devices: []*model.Device{},
+			err:     service.ErrUndeliverable,
+		},
+		{
+			name: "unknown platform",
+			devices: []*model.Device{
+				{Platform: "web", Token: "web-1"},
+			},
+			err: service.ErrUndeliverable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &deviceStoreMock{devices: tt.devices}
+			c := NewPushChannel(store, service.PushProviders{
+				&pushProviderMock{platform: model.PlatformFCM, errs: tt.errs},
+				&pushProviderMock{platform: model.PlatformAPNs, errs: tt.errs},
+			})
+
+			err := c.Send(context.Background(), &model.User{ID: 1}, &model.Notification{ID: 1})
+			if errors.Cause(err) != tt.err {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			for _, tokens := range store.deleted {
+				sort.Strings(tokens)
+			}
+
+			if len(tt.deleted) == 0 && len(store.deleted) == 0 {
+				return
+			}
+
+			if !reflect.DeepEqual(store.deleted, tt.deleted) {
+				t.Fatalf("expected deleted tokens %v, got %v", tt.deleted, store.deleted)
+			}
+		})
+	}
+}
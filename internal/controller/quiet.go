@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+const minutesPerDay = 24 * 60
+
+// ErrInvalidQuietHours is returned when quiet hours are not in HH:MM format or their window is empty.
+var ErrInvalidQuietHours = errors.New("quiet hours must be different HH:MM times")
+
+// SetQuietHours validates and stores user's quiet hours.
+func (ha *App) SetQuietHours(ctx context.Context, user *model.User, quietHours *model.QuietHours) (*model.Preferences, error) {
+	if _, err := parseQuietHours(quietHours); err != nil {
+		return nil, err
+	}
+
+	err := ha.preferenceStore.SetQuietHours(ctx, user.ID, quietHours)
+	if err != nil {
+		return nil, errors.Wrapf(err, "setting quiet hours of user %d", user.ID)
+	}
+
+	return ha.GetPreferences(ctx, user)
+}
+
+// DeleteQuietHours turns user's quiet hours off.
+func (ha *App) DeleteQuietHours(ctx context.Context, user *model.User) error {
+	err := ha.preferenceStore.DeleteQuietHours(ctx, user.ID)
+	if err != nil {
+		return errors.Wrapf(err, "deleting quiet hours of user %d", user.ID)
+	}
+
+	return nil
+}
+
+// quietWindow is parsed quiet hours, start and end are minutes since local midnight
+type quietWindow struct {
+	loc   *time.Location
+	start int
+	end   int
+}
+
+func parseQuietHours(quietHours *model.QuietHours) (*quietWindow, error) {
+	loc, err := time.LoadLocation(quietHours.Timezone)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidTimezone, err.Error())
+	}
+
+	start, ok := parseClock(quietHours.Start)
+	if !ok {
+		return nil, ErrInvalidQuietHours
+	}
+
+	end, ok := parseClock(quietHours.End)
+	if !ok || start == end {
+		return nil, ErrInvalidQuietHours
+	}
+
+	return &quietWindow{loc: loc, start: start, end: end}, nil
+}
+
+// parseClock parses HH:MM time into minutes since midnight.
+func parseClock(s string) (int, bool) {
+	c, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, false
+	}
+
+	return c.Hour()*60 + c.Minute(), true
+}
+
+// until returns end of window if t is within window. Window bounds are wall clock times of
+// window's location, so window is shorter or longer on days of DST transitions.
+func (w *quietWindow) until(t time.Time) (time.Time, bool) {
+	y, m, d := t.In(w.loc).Date()
+
+	// Window that started yesterday may still last if it ends on the next day
+	for _, day := range []int{d - 1, d} {
+		start := wallClock(w.loc, y, m, day, w.start)
+
+		endDay := day
+		if w.end <= w.start {
+			endDay++
+		}
+		end := wallClock(w.loc, y, m, endDay, w.end)
+
+		if !t.Before(start) && t.Before(end) {
+			return end, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// wallClock returns time of day given in minutes since midnight in location loc.
+// Time skipped by DST transition is moved forward by the length of the gap.
+func wallClock(loc *time.Location, y int, m time.Month, d int, minutes int) time.Time {
+	t := time.Date(y, m, d, minutes/60, minutes%60, 0, 0, loc)
+
+	// Wall clock time skipped by DST transition is normalized using offset after transition,
+	// move it forward by the length of the gap instead
+	if wall := t.Hour()*60 + t.Minute(); wall != minutes%minutesPerDay {
+		t = t.Add(time.Duration((minutes-wall+minutesPerDay)%minutesPerDay) * time.Minute)
+	}
+
+	return t
+}
+
+// deferral is returned instead of sending delivery which falls into quiet hours
+type deferral struct {
+	until time.Time
+}
+
+func (d *deferral) Error() string {
+	return "delivery is deferred until " + d.until.Format(time.RFC3339)
+}
@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+func TestQuietWindowUntil(t *testing.T) {
+	tests := []struct {
+		name     string
+		timezone string
+		start    string
+		end      string
+		at       string
+		// until is empty when at is outside of window
+		until string
+	}{
+		{
+			name:     "within day window",
+			timezone: "Asia/Tokyo",
+			start:    "09:00",
+			end:      "17:00",
+			at:       "2026-06-01T12:00:00+09:00",
+			until:    "2026-06-01T17:00:00+09:00",
+		},
+		{
+			name:     "after day window",
+			timezone: "Asia/Tokyo",
+			start:    "09:00",
+			end:      "17:00",
+			at:       "2026-06-01T17:00:00+09:00",
+		},
+		{
+			name:     "before midnight of window crossing midnight",
+			timezone: "Asia/Tokyo",
+			start:    "22:00",
+			end:      "07:00",
+			at:       "2026-06-01T14:00:00Z",
+			until:    "2026-06-02T07:00:00+09:00",
+		},
+		{
+			name:     "after midnight of window crossing midnight",
+			timezone: "Asia/Tokyo",
+			start:    "22:00",
+			end:      "07:00",
+			at:       "2026-06-02T06:59:00+09:00",
+			until:    "2026-06-02T07:00:00+09:00",
+		},
+		{
+			name:     "outside of window crossing midnight",
+			timezone: "Asia/Tokyo",
+			start:    "22:00",
+			end:      "07:00",
+			at:       "2026-06-01T21:59:00+09:00",
+		},
+		{
+			name:     "spring forward within night window",
+			timezone: "America/New_York",
+			start:    "22:00",
+			end:      "07:00",
+			at:       "2026-03-08T01:00:00-05:00",
+			until:    "2026-03-08T07:00:00-04:00",
+		},
+		{
+			name:     "spring forward after night window",
+			timezone: "America/New_York",
+			start:    "22:00",
+			end:      "07:00",
+			at:       "2026-03-08T07:00:00-04:00",
+		},
+		{
+			name:     "spring forward end in gap",
+			timezone: "America/New_York",
+			start:    "01:30",
+			end:      "02:30",
+			at:       "2026-03-08T01:45:00-05:00",
+			until:    "2026-03-08T03:30:00-04:00",
+		},
+		{
+			name:     "spring forward end in gap after transition",
+			timezone: "America/New_York",
+			start:    "01:30",
+			end:      "02:30",
+			at:       "2026-03-08T03:20:00-04:00",
+			until:    "2026-03-08T03:30:00-04:00",
+		},
+		{
+			name:     "spring forward window in gap",
+			timezone: "America/New_York",
+			start:    "02:00",
+			end:      "02:30",
+			at:       "2026-03-08T03:10:00-04:00",
+			until:    "2026-03-08T03:30:00-04:00",
+		},
+		{
+			name:     "spring forward before window in gap",
+			timezone: "America/New_York",
+			start:    "02:00",
+			end:      "02:30",
+			at:       "2026-03-08T01:59:00-05:00",
+		},
+		{
+			name:     "fall back within night window",
+			timezone: "America/New_York",
+			start:    "22:00",
+			end:      "07:00",
+			at:       "2026-11-01T01:30:00-05:00",
+			until:    "2026-11-01T07:00:00-05:00",
+		},
+		{
+			name:     "fall back repeated hour",
+			timezone: "America/New_York",
+			start:    "01:30",
+			end:      "02:30",
+			at:       "2026-11-01T01:45:00-05:00",
+			until:    "2026-11-01T02:30:00-05:00",
+		},
+		{
+			name:     "fall back first pass of repeated hour",
+			timezone: "America/New_York",
+			start:    "01:30",
+			end:      "02:30",
+			at:       "2026-11-01T01:45:00-04:00",
+			until:    "2026-11-01T02:30:00-05:00",
+		},
+		{
+			name:     "spring forward at midnight",
+			timezone: "America/Santiago",
+			start:    "00:30",
+			end:      "06:00",
+			at:       "2026-09-06T01:45:00-03:00",
+			until:    "2026-09-06T06:00:00-03:00",
+		},
+		{
+			name:     "spring forward at midnight before window",
+			timezone: "America/Santiago",
+			start:    "00:30",
+			end:      "06:00",
+			at:       "2026-09-05T23:45:00-04:00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := parseQuietHours(&model.QuietHours{
+				Timezone: tt.timezone,
+				Start:    tt.start,
+				End:      tt.end,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			until, ok := w.until(parseTime(t, tt.at))
+
+			if tt.until == "" {
+				if ok {
+					t.Fatalf("expected %s to be outside of window, got until %s", tt.at, until)
+				}
+				return
+			}
+
+			if !ok {
+				t.Fatalf("expected %s to be within window", tt.at)
+			}
+
+			if expected := parseTime(t, tt.until); !until.Equal(expected) {
+				t.Fatalf("expected until %s, got %s", expected, until)
+			}
+		})
+	}
+}
+
+func TestParseQuietHours(t *testing.T) {
+	tests := []struct {
+		name       string
+		quietHours model.QuietHours
+		err        error
+	}{
+		{"valid", model.QuietHours{Timezone: "Europe/Berlin", Start: "22:00", End: "07:00"}, nil},
+		{"unknown timezone", model.QuietHours{Timezone: "Mars/Olympus", Start: "22:00", End: "07:00"}, ErrInvalidTimezone},
+		{"invalid start", model.QuietHours{Timezone: "UTC", Start: "24:00", End: "07:00"}, ErrInvalidQuietHours},
+		{"invalid end", model.QuietHours{Timezone: "UTC", Start: "22:00", End: "7"}, ErrInvalidQuietHours},
+		{"empty window", model.QuietHours{Timezone: "UTC", Start: "22:00", End: "22:00"}, ErrInvalidQuietHours},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseQuietHours(&tt.quietHours)
+			if errors.Cause(err) != tt.err {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func parseTime(t *testing.T, s string) time.Time {
+	t.Helper()
+
+	v, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return v
+}
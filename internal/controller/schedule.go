@@ -0,0 +1,296 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/fx"
+
+	"github.com/hummerd/gophercon/internal/config"
+	"github.com/hummerd/gophercon/internal/cron"
+	"github.com/hummerd/gophercon/internal/dataprovider"
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+const (
+	maxPreviewRuns = 100
+)
+
+var (
+	// ErrInvalidCron is returned when schedule's cron expression can't be parsed.
+	ErrInvalidCron = errors.New("invalid cron expression")
+	// ErrInvalidTimezone is returned when schedule's timezone is unknown.
+	ErrInvalidTimezone = errors.New("unknown timezone")
+	// ErrInvalidCatchUp is returned when schedule's catch-up policy is unknown.
+	ErrInvalidCatchUp = errors.New("unknown catch-up policy")
+)
+
+// CreateSchedule validates and stores new schedule, schedule's first run is computed from now.
+func (ha *App) CreateSchedule(ctx context.Context, schedule *model.Schedule) error {
+	if err := ha.validateSchedule(schedule); err != nil {
+		return err
+	}
+
+	next, err := nextRun(schedule, time.Now())
+	if err != nil {
+		return err
+	}
+	schedule.NextRunAt = next
+
+	err = ha.scheduleStore.Insert(ctx, schedule)
+	if err != nil {
+		return errors.Wrap(err, "creating schedule")
+	}
+
+	return nil
+}
+
+// UpdateSchedule replaces schedule's definition, next run is recomputed from now.
+func (ha *App) UpdateSchedule(ctx context.Context, schedule *model.Schedule) error {
+	if err := ha.validateSchedule(schedule); err != nil {
+		return err
+	}
+
+	next, err := nextRun(schedule, time.Now())
+	if err != nil {
+		return err
+	}
+	schedule.NextRunAt = next
+
+	err = ha.scheduleStore.Update(ctx, schedule)
+	if err != nil {
+		return errors.Wrapf(err, "updating schedule %d", schedule.ID)
+	}
+
+	return nil
+}
+
+// GetSchedule returns schedule by id.
+func (ha *App) GetSchedule(ctx context.Context, id int) (*model.Schedule, error) {
+	schedule, err := ha.scheduleStore.Get(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting schedule %d", id)
+	}
+
+	return schedule, nil
+}
+
+// ListSchedules returns all schedules.
+func (ha *App) ListSchedules(ctx context.Context) ([]*model.Schedule, error) {
+	schedules, err := ha.scheduleStore.List(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing schedules")
+	}
+
+	return schedules, nil
+}
+
+// DeleteSchedule deletes schedule.
+func (ha *App) DeleteSchedule(ctx context.Context, id int) error {
+	err := ha.scheduleStore.Delete(ctx, id)
+	if err != nil {
+		return errors.Wrapf(err, "deleting schedule %d", id)
+	}
+
+	return nil
+}
+
+// PauseSchedule stops schedule from producing notifications.
+func (ha *App) PauseSchedule(ctx context.Context, id int) error {
+	schedule, err := ha.scheduleStore.Get(ctx, id)
+	if err != nil {
+		return errors.Wrapf(err, "getting schedule %d", id)
+	}
+
+	err = ha.scheduleStore.SetPaused(ctx, id, true, schedule.NextRunAt)
+	if err != nil {
+		return errors.Wrapf(err, "pausing schedule %d", id)
+	}
+
+	return nil
+}
+
+// ResumeSchedule resumes paused schedule, runs missed while schedule was paused are not produced.
+func (ha *App) ResumeSchedule(ctx context.Context, id int) error {
+	schedule, err := ha.scheduleStore.Get(ctx, id)
+	if err != nil {
+		return errors.Wrapf(err, "getting schedule %d", id)
+	}
+
+	next, err := nextRun(schedule, time.Now())
+	if err != nil {
+		return err
+	}
+
+	err = ha.scheduleStore.SetPaused(ctx, id, false, next)
+	if err != nil {
+		return errors.Wrapf(err, "resuming schedule %d", id)
+	}
+
+	return nil
+}
+
+// PreviewRuns returns up to count next run times of cron expression in timezone after now.
+func (ha *App) PreviewRuns(cronExpr, timezone string, count int) ([]time.Time, error) {
+	cs, loc, err := parseSchedule(cronExpr, timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	if count <= 0 || count > maxPreviewRuns {
+		count = maxPreviewRuns
+	}
+
+	runs := make([]time.Time, 0, count)
+	t := time.Now().In(loc)
+
+	for len(runs) < count {
+		t = cs.Next(t)
+		if t.IsZero() {
+			break
+		}
+		runs = append(runs, t)
+	}
+
+	return runs, nil
+}
+
+func (ha *App) validateSchedule(schedule *model.Schedule) error {
+	if schedule.Title == "" || schedule.Body == "" || schedule.Type == "" {
+		return ErrMissingContent
+	}
+
+	if schedule.Priority == "" {
+		schedule.Priority = model.PriorityNormal
+	}
+
+	if !schedule.Priority.Valid() {
+		return ErrInvalidPriority
+	}
+
+	if schedule.Timezone == "" {
+		schedule.Timezone = "UTC"
+	}
+
+	if schedule.CatchUp == "" {
+		schedule.CatchUp = ha.catchUp
+	}
+
+	if schedule.CatchUp != model.CatchUpSkip && schedule.CatchUp != model.CatchUpAll {
+		return ErrInvalidCatchUp
+	}
+
+	_, _, err := parseSchedule(schedule.Cron, schedule.Timezone)
+	return err
+}
+
+func parseSchedule(cronExpr, timezone string) (*cron.Schedule, *time.Location, error) {
+	cs, err := cron.Parse(cronExpr)
+	if err != nil {
+		return nil, nil, errors.Wrap(ErrInvalidCron, err.Error())
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, nil, errors.Wrap(ErrInvalidTimezone, err.Error())
+	}
+
+	return cs, loc, nil
+}
+
+// nextRun returns schedule's first run after t, nil is returned if there are no more runs.
+func nextRun(schedule *model.Schedule, t time.Time) (*time.Time, error) {
+	cs, loc, err := parseSchedule(schedule.Cron, schedule.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	next := cs.Next(t.In(loc))
+	if next.IsZero() {
+		return nil, nil
+	}
+
+	return &next, nil
+}
+
+// NewScheduler creates Scheduler running due schedules every schedule interval.
+func NewScheduler(
+	lc fx.Lifecycle,
+	cfg *config.Config,
+	scheduleStore dataprovider.ScheduleStore,
+) *Scheduler {
+	s := &Scheduler{
+		scheduleStore: scheduleStore,
+		batchSize:     cfg.DispatchBatchSize,
+		maxCatchUp:    cfg.ScheduleMaxCatchUp,
+		language:      cfg.SearchLanguage,
+	}
+
+	appendTicker(lc, "notifications scheduler", cfg.ScheduleInterval, func(ctx context.Context) {
+		processBatches(ctx, s.batchSize, "can not run due schedules", s.runDue)
+	})
+
+	return s
+}
+
+// Scheduler produces notifications of due schedules, produced notifications are
+// scheduled to their run times and published by Dispatcher.
+type Scheduler struct {
+	scheduleStore dataprovider.ScheduleStore
+
+	batchSize  int
+	maxCatchUp int
+	// language is a search language of produced notifications
+	language string
+}
+
+// runDue produces notifications of batch of due schedules.
+func (s *Scheduler) runDue(ctx context.Context) (int, error) {
+	return s.scheduleStore.RunDue(ctx, s.batchSize, s.plan)
+}
+
+// plan produces notifications for schedule's runs due at now according to catch-up policy.
+// With CatchUpSkip only the latest due run produces notification, with CatchUpAll
+// every due run does but no more than maxCatchUp latest runs.
+func (s *Scheduler) plan(schedule *model.Schedule, now time.Time) ([]*model.Notification, *time.Time, error) {
+	cs, loc, err := parseSchedule(schedule.Cron, schedule.Timezone)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var due []time.Time
+
+	t := schedule.NextRunAt.In(loc)
+	for !t.IsZero() && !t.After(now) {
+		due = append(due, t)
+		if len(due) > s.maxCatchUp {
+			due = due[1:]
+		}
+
+		t = cs.Next(t)
+	}
+
+	if schedule.CatchUp == model.CatchUpSkip && len(due) > 1 {
+		due = due[len(due)-1:]
+	}
+
+	notifications := make([]*model.Notification, len(due))
+	for i := range due {
+		notifications[i] = &model.Notification{
+			UserID:    schedule.UserID,
+			Type:      schedule.Type,
+			Title:     schedule.Title,
+			Body:      schedule.Body,
+			Priority:  schedule.Priority,
+			PublishAt: &due[i],
+			Language:  s.language,
+		}
+	}
+
+	if t.IsZero() {
+		return notifications, nil, nil
+	}
+
+	return notifications, &t, nil
+}
@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+func TestSchedulerPlan(t *testing.T) {
+	now := parseTime(t, "2026-01-01T14:30:00Z")
+
+	tests := []struct {
+		name       string
+		cron       string
+		catchUp    string
+		maxCatchUp int
+		nextRunAt  string
+		// runs are publish times of planned notifications
+		runs []string
+		// next is empty when schedule has no more runs
+		next string
+	}{
+		{
+			name:       "not due",
+			cron:       "0 * * * *",
+			catchUp:    model.CatchUpSkip,
+			maxCatchUp: 10,
+			nextRunAt:  "2026-01-01T15:00:00Z",
+			next:       "2026-01-01T15:00:00Z",
+		},
+		{
+			name:       "single due run",
+			cron:       "0 * * * *",
+			catchUp:    model.CatchUpSkip,
+			maxCatchUp: 10,
+			nextRunAt:  "2026-01-01T14:00:00Z",
+			runs:       []string{"2026-01-01T14:00:00Z"},
+			next:       "2026-01-01T15:00:00Z",
+		},
+		{
+			name:       "skip missed runs",
+			cron:       "0 * * * *",
+			catchUp:    model.CatchUpSkip,
+			maxCatchUp: 10,
+			nextRunAt:  "2026-01-01T10:00:00Z",
+			runs:       []string{"2026-01-01T14:00:00Z"},
+			next:       "2026-01-01T15:00:00Z",
+		},
+		{
+			name:       "all missed runs",
+			cron:       "0 * * * *",
+			catchUp:    model.CatchUpAll,
+			maxCatchUp: 10,
+			nextRunAt:  "2026-01-01T12:00:00Z",
+			runs: []string{
+				"2026-01-01T12:00:00Z",
+				"2026-01-01T13:00:00Z",
+				"2026-01-01T14:00:00Z",
+			},
+			next: "2026-01-01T15:00:00Z",
+		},
+		{
+			name:       "all missed runs are limited",
+			cron:       "0 * * * *",
+			catchUp:    model.CatchUpAll,
+			maxCatchUp: 2,
+			nextRunAt:  "2026-01-01T10:00:00Z",
+			runs: []string{
+				"2026-01-01T13:00:00Z",
+				"2026-01-01T14:00:00Z",
+			},
+			next: "2026-01-01T15:00:00Z",
+		},
+		{
+			name:       "last run",
+			cron:       "0 0 30 2 *",
+			catchUp:    model.CatchUpSkip,
+			maxCatchUp: 10,
+			nextRunAt:  "2026-01-01T14:00:00Z",
+			runs:       []string{"2026-01-01T14:00:00Z"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Scheduler{maxCatchUp: tt.maxCatchUp, language: "simple"}
+
+			userID := int64(1)
+			nextRunAt := parseTime(t, tt.nextRunAt)
+
+			notifications, next, err := s.plan(&model.Schedule{
+				Cron:      tt.cron,
+				Timezone:  "UTC",
+				CatchUp:   tt.catchUp,
+				UserID:    &userID,
+				Type:      "reminder",
+				Title:     "title",
+				Body:      "body",
+				Priority:  model.PriorityNormal,
+				NextRunAt: &nextRunAt,
+			}, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(notifications) != len(tt.runs) {
+				t.Fatalf("expected %d notifications, got %d", len(tt.runs), len(notifications))
+			}
+
+			for i, n := range notifications {
+				if expected := parseTime(t, tt.runs[i]); !n.PublishAt.Equal(expected) {
+					t.Fatalf("expected notification %d published at %s, got %s", i, expected, n.PublishAt)
+				}
+
+				if n.UserID != &userID || n.Type != "reminder" || n.Priority != model.PriorityNormal || n.Language != "simple" {
+					t.Fatalf("unexpected notification %+v", n)
+				}
+			}
+
+			if tt.next == "" {
+				if next != nil {
+					t.Fatalf("expected no next run, got %s", next)
+				}
+				return
+			}
+
+			if next == nil {
+				t.Fatal("expected next run")
+			}
+
+			if expected := parseTime(t, tt.next); !next.Equal(expected) {
+				t.Fatalf("expected next run at %s, got %s", expected, next)
+			}
+		})
+	}
+}
+
+func TestSchedulerPlanInvalid(t *testing.T) {
+	s := &Scheduler{maxCatchUp: 10}
+
+	now := time.Now()
+
+	_, _, err := s.plan(&model.Schedule{Cron: "bad", Timezone: "UTC", NextRunAt: &now}, now)
+	if errors.Cause(err) != ErrInvalidCron {
+		t.Fatalf("expected %v, got %v", ErrInvalidCron, err)
+	}
+}
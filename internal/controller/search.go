@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+const maxSearchQueryLength = 256
+
+var (
+	// ErrInvalidSearchQuery is returned when search query is empty or too long.
+	ErrInvalidSearchQuery = errors.New("search query must be from 1 to 256 characters")
+	// ErrInvalidLanguage is returned when language is not a known text search configuration.
+	ErrInvalidLanguage = errors.New("unknown language")
+)
+
+// searchLanguages are text search configurations built into postgres
+var searchLanguages = map[string]struct{}{
+	"simple":     {},
+	"arabic":     {},
+	"armenian":   {},
+	"basque":     {},
+	"catalan":    {},
+	"danish":     {},
+	"dutch":      {},
+	"english":    {},
+	"finnish":    {},
+	"french":     {},
+	"german":     {},
+	"greek":      {},
+	"hindi":      {},
+	"hungarian":  {},
+	"indonesian": {},
+	"irish":      {},
+	"italian":    {},
+	"lithuanian": {},
+	"nepali":     {},
+	"norwegian":  {},
+	"portuguese": {},
+	"romanian":   {},
+	"russian":    {},
+	"serbian":    {},
+	"spanish":    {},
+	"swedish":    {},
+	"tamil":      {},
+	"turkish":    {},
+	"yiddish":    {},
+}
+
+// SearchNotifications returns page of notifications visible to user that match full-text query,
+// the most relevant go first. Query is parsed with filter's language, configured language is used by default.
+// Results are not localized as query is matched against notifications' own title and body.
+// Cursor pointing to the next page is returned, it is nil for the last page.
+func (ha *App) SearchNotifications(
+	ctx context.Context,
+	user *model.User,
+	filter *model.SearchFilter,
+) ([]*model.SearchResult, *model.SearchCursor, error) {
+	filter.Query = strings.TrimSpace(filter.Query)
+	if filter.Query == "" || len(filter.Query) > maxSearchQueryLength {
+		return nil, nil, ErrInvalidSearchQuery
+	}
+
+	if filter.Language == "" {
+		filter.Language = ha.searchLanguage
+	}
+
+	if _, ok := searchLanguages[filter.Language]; !ok {
+		return nil, nil, ErrInvalidLanguage
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultPageSize
+	}
+	if filter.Limit > maxPageSize {
+		filter.Limit = maxPageSize
+	}
+
+	user, err := ha.inboxUser(ctx, user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pageSize := filter.Limit
+	// Request one extra result to find out whether next page exists
+	filter.Limit++
+
+	results, err := ha.notificationStore.Search(ctx, user, filter)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "searching notifications for user %d", user.ID)
+	}
+
+	if len(results) <= pageSize {
+		return results, nil, nil
+	}
+
+	results = results[:pageSize]
+	last := results[pageSize-1]
+
+	return results, &model.SearchCursor{
+		Rank: last.Rank,
+		ID:   last.ID,
+	}, nil
+}
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"text/template"
+
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+var (
+	// ErrInvalidTemplate is returned when template can't be parsed.
+	ErrInvalidTemplate = errors.New("invalid template")
+	// ErrMissingVariable is returned when variable referenced by template is not provided.
+	ErrMissingVariable = errors.New("missing template variable")
+)
+
+// CreateTemplate validates and stores new template.
+func (ha *App) CreateTemplate(ctx context.Context, tmpl *model.Template) error {
+	if err := validateTemplate(tmpl); err != nil {
+		return err
+	}
+
+	err := ha.templateStore.Insert(ctx, tmpl)
+	if err != nil {
+		return errors.Wrapf(err, "creating template %q", tmpl.Type)
+	}
+
+	return nil
+}
+
+// UpdateTemplate validates and replaces title and body of existing template.
+func (ha *App) UpdateTemplate(ctx context.Context, tmpl *model.Template) error {
+	if err := validateTemplate(tmpl); err != nil {
+		return err
+	}
+
+	err := ha.templateStore.Update(ctx, tmpl)
+	if err != nil {
+		return errors.Wrapf(err, "updating template %q", tmpl.Type)
+	}
+
+	return nil
+}
+
+// GetTemplate returns template for notification type.
+func (ha *App) GetTemplate(ctx context.Context, templateType string) (*model.Template, error) {
+	tmpl, err := ha.templateStore.Get(ctx, templateType)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting template %q", templateType)
+	}
+
+	return tmpl, nil
+}
+
+// ListTemplates returns all templates.
+func (ha *App) ListTemplates(ctx context.Context) ([]*model.Template, error) {
+	templates, err := ha.templateStore.List(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing templates")
+	}
+
+	return templates, nil
+}
+
+// DeleteTemplate deletes template for notification type.
+func (ha *App) DeleteTemplate(ctx context.Context, templateType string) error {
+	err := ha.templateStore.Delete(ctx, templateType)
+	if err != nil {
+		return errors.Wrapf(err, "deleting template %q", templateType)
+	}
+
+	return nil
+}
+
+// RenderNotification renders template with variables into notification of template's type.
+// Returned notification is not saved.
+func (ha *App) RenderNotification(ctx context.Context, templateType string, vars map[string]string) (*model.Notification, error) {
+	tmpl, err := ha.templateStore.Get(ctx, templateType)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting template %q", templateType)
+	}
+
+	title, err := render(tmpl.Title, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := render(tmpl.Body, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Notification{
+		Type:  tmpl.Type,
+		Title: title,
+		Body:  body,
+	}, nil
+}
+
+func validateTemplate(tmpl *model.Template) error {
+	if tmpl.Type == "" || tmpl.Title == "" || tmpl.Body == "" {
+		return ErrMissingContent
+	}
+
+	for _, src := range []string{tmpl.Title, tmpl.Body} {
+		if _, err := parseTemplate(src); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func parseTemplate(src string) (*template.Template, error) {
+	t, err := template.New("").Option("missingkey=error").Parse(src)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidTemplate, err.Error())
+	}
+
+	return t, nil
+}
+
+func render(src string, vars map[string]string) (string, error) {
+	t, err := parseTemplate(src)
+	if err != nil {
+		return "", err
+	}
+
+	if vars == nil {
+		vars = map[string]string{}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := t.Execute(buf, vars); err != nil {
+		return "", errors.Wrap(ErrMissingVariable, err.Error())
+	}
+
+	return buf.String(), nil
+}
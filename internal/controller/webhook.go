@@ -0,0 +1,310 @@
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"go.uber.org/fx"
+
+	"github.com/hummerd/gophercon/internal/config"
+	"github.com/hummerd/gophercon/internal/dataprovider"
+	"github.com/hummerd/gophercon/internal/model"
+	"github.com/hummerd/gophercon/internal/service"
+)
+
+const (
+	defaultDeliveriesPage = 50
+	maxDeliveriesPage     = 500
+
+	// deliveryLease is a time claimed delivery is not claimed again, it must exceed request timeout
+	deliveryLease = 2 * time.Minute
+)
+
+var (
+	// ErrInvalidWebhookURL is returned when webhook's url is not absolute http(s) url.
+	ErrInvalidWebhookURL = errors.New("webhook url must be absolute http or https url")
+	// ErrUnknownEvent is returned when webhook is subscribed to unknown event type.
+	ErrUnknownEvent = errors.New("unknown event type")
+)
+
+// webhookEvents are event types webhooks can subscribe to
+var webhookEvents = map[string]struct{}{
+	model.EventNotificationPublished: {},
+}
+
+// CreateWebhook validates and stores new webhook, random secret is generated when webhook has none.
+func (ha *App) CreateWebhook(ctx context.Context, webhook *model.Webhook) error {
+	if err := validateWebhook(webhook); err != nil {
+		return err
+	}
+
+	if webhook.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return errors.Wrap(err, "generating webhook secret")
+		}
+		webhook.Secret = hex.EncodeToString(secret)
+	}
+
+	err := ha.webhookStore.Insert(ctx, webhook)
+	if err != nil {
+		return errors.Wrap(err, "creating webhook")
+	}
+
+	return nil
+}
+
+// UpdateWebhook replaces webhook's url, filters and state, secret is kept unless new one is set.
+func (ha *App) UpdateWebhook(ctx context.Context, webhook *model.Webhook) error {
+	if err := validateWebhook(webhook); err != nil {
+		return err
+	}
+
+	err := ha.webhookStore.Update(ctx, webhook)
+	if err != nil {
+		return errors.Wrapf(err, "updating webhook %d", webhook.ID)
+	}
+
+	return nil
+}
+
+// GetWebhook returns webhook by id.
+func (ha *App) GetWebhook(ctx context.Context, id int) (*model.Webhook, error) {
+	webhook, err := ha.webhookStore.Get(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting webhook %d", id)
+	}
+
+	return webhook, nil
+}
+
+// ListWebhooks returns all webhooks.
+func (ha *App) ListWebhooks(ctx context.Context) ([]*model.Webhook, error) {
+	webhooks, err := ha.webhookStore.List(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing webhooks")
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook deletes webhook along with its delivery history.
+func (ha *App) DeleteWebhook(ctx context.Context, id int) error {
+	err := ha.webhookStore.Delete(ctx, id)
+	if err != nil {
+		return errors.Wrapf(err, "deleting webhook %d", id)
+	}
+
+	return nil
+}
+
+// ListWebhookDeliveries returns up to limit latest deliveries of webhook.
+func (ha *App) ListWebhookDeliveries(ctx context.Context, id int, limit int) ([]*model.WebhookDelivery, error) {
+	if limit <= 0 {
+		limit = defaultDeliveriesPage
+	}
+	if limit > maxDeliveriesPage {
+		limit = maxDeliveriesPage
+	}
+
+	deliveries, err := ha.webhookStore.ListDeliveries(ctx, id, limit)
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing deliveries of webhook %d", id)
+	}
+
+	return deliveries, nil
+}
+
+// ListWebhookAttempts returns attempts of webhook's delivery.
+func (ha *App) ListWebhookAttempts(ctx context.Context, id int, deliveryID int) ([]*model.WebhookAttempt, error) {
+	attempts, err := ha.webhookStore.ListAttempts(ctx, id, deliveryID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing attempts of webhook %d delivery %d", id, deliveryID)
+	}
+
+	return attempts, nil
+}
+
+func validateWebhook(webhook *model.Webhook) error {
+	u, err := url.Parse(webhook.URL)
+	if err != nil || !u.IsAbs() || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidWebhookURL
+	}
+
+	for _, e := range webhook.Events {
+		if _, ok := webhookEvents[e]; !ok {
+			return errors.Wrap(ErrUnknownEvent, e)
+		}
+	}
+
+	return nil
+}
+
+// NewWebhookDeliverer creates WebhookDeliverer sending due deliveries every webhook interval.
+func NewWebhookDeliverer(
+	lc fx.Lifecycle,
+	cfg *config.Config,
+	webhookStore dataprovider.WebhookStore,
+	sender service.WebhookSender,
+) *WebhookDeliverer {
+	d := &WebhookDeliverer{
+		webhookStore: webhookStore,
+		sender:       sender,
+		batchSize:    cfg.WebhookBatchSize,
+		maxAttempts:  cfg.WebhookMaxAttempts,
+		retryBase:    cfg.WebhookRetryBase,
+		retryMax:     cfg.WebhookRetryMax,
+	}
+
+	appendTicker(lc, "webhook deliverer", cfg.WebhookInterval, func(ctx context.Context) {
+		processBatches(ctx, d.batchSize, "can not claim due webhook deliveries", d.deliverDue)
+	})
+
+	return d
+}
+
+// WebhookDeliverer enqueues deliveries of published events to subscribed webhooks and sends them.
+// Failed deliveries are retried with exponential backoff until attempts are exhausted.
+type WebhookDeliverer struct {
+	webhookStore dataprovider.WebhookStore
+	sender       service.WebhookSender
+
+	batchSize   int
+	maxAttempts int
+	retryBase   time.Duration
+	retryMax    time.Duration
+}
+
+// webhookPayload is a body of webhook delivery
+type webhookPayload struct {
+	Event        string              `json:"event"`
+	At           time.Time           `json:"at"`
+	UserID       *int64              `json:"user_id,omitempty"`
+	Notification *model.Notification `json:"notification"`
+}
+
+// Publish implements service.EventPublisher, it enqueues deliveries of event to matching webhooks.
+func (d *WebhookDeliverer) Publish(ctx context.Context, event *model.Event) error {
+	webhooks, err := d.webhookStore.List(ctx)
+	if err != nil {
+		return errors.Wrap(err, "listing webhooks")
+	}
+
+	payload, err := json.Marshal(&webhookPayload{
+		Event:        event.Type,
+		At:           event.At,
+		UserID:       event.Notification.UserID,
+		Notification: event.Notification,
+	})
+	if err != nil {
+		return errors.Wrap(err, "encoding webhook payload")
+	}
+
+	now := time.Now()
+	deliveries := make([]*model.WebhookDelivery, 0)
+
+	for _, w := range webhooks {
+		if !w.Matches(event) {
+			continue
+		}
+
+		deliveries = append(deliveries, &model.WebhookDelivery{
+			WebhookID:     w.ID,
+			Event:         event.Type,
+			Payload:       payload,
+			Status:        model.DeliveryPending,
+			NextAttemptAt: &now,
+		})
+	}
+
+	err = d.webhookStore.InsertDeliveries(ctx, deliveries)
+	if err != nil {
+		return errors.Wrap(err, "enqueuing webhook deliveries")
+	}
+
+	return nil
+}
+
+// deliverDue sends batch of due deliveries concurrently.
+func (d *WebhookDeliverer) deliverDue(ctx context.Context) (int, error) {
+	deliveries, err := d.webhookStore.ClaimDue(ctx, d.batchSize, deliveryLease)
+	if err != nil {
+		return 0, err
+	}
+
+	inParallel(len(deliveries), func(i int) {
+		d.deliver(ctx, deliveries[i])
+	})
+
+	return len(deliveries), nil
+}
+
+// deliver makes single attempt of delivery and records its result.
+func (d *WebhookDeliverer) deliver(ctx context.Context, delivery *model.WebhookDelivery) {
+	logger := log.With().
+		Int("webhook_id", delivery.WebhookID).
+		Int("delivery_id", delivery.ID).
+		Logger()
+
+	webhook, err := d.webhookStore.Get(ctx, delivery.WebhookID)
+	if err != nil {
+		// Deleted webhook's deliveries are deleted as well
+		logger.Error().Err(err).Msg("can not get webhook of delivery")
+		return
+	}
+
+	start := time.Now()
+	code, err := d.sender.Send(ctx, webhook, delivery)
+	if ctx.Err() != nil {
+		return
+	}
+
+	attempt := &model.WebhookAttempt{
+		DeliveryID: delivery.ID,
+		Attempt:    delivery.Attempts + 1,
+		StatusCode: code,
+		DurationMs: int64(time.Since(start) / time.Millisecond),
+		At:         start,
+	}
+
+	delivery.Attempts++
+	delivery.NextAttemptAt = nil
+
+	switch {
+	case err == nil:
+		delivery.Status = model.DeliverySucceeded
+	case retryable(code) && delivery.Attempts < d.maxAttempts:
+		attempt.Error = err.Error()
+		next := time.Now().Add(backoff(d.retryBase, d.retryMax, delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	default:
+		attempt.Error = err.Error()
+		delivery.Status = model.DeliveryFailed
+	}
+
+	err = d.webhookStore.RecordAttempt(ctx, delivery, attempt)
+	if err != nil {
+		logger.Error().Err(err).Msg("can not record webhook delivery attempt")
+		return
+	}
+
+	if delivery.Status == model.DeliveryFailed {
+		logger.Warn().Int("attempts", delivery.Attempts).Msg("webhook delivery failed")
+	}
+}
+
+// retryable reports whether delivery failed with status code may succeed later,
+// zero code means endpoint was not reached at all.
+func retryable(code int) bool {
+	return code == 0 ||
+		code == http.StatusRequestTimeout ||
+		code == http.StatusTooManyRequests ||
+		code >= http.StatusInternalServerError
+}
@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hummerd/gophercon/internal/dataprovider"
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+type webhookStoreMock struct {
+	dataprovider.WebhookStore
+
+	attempts []*model.WebhookAttempt
+}
+
+func (s *webhookStoreMock) Get(ctx context.Context, id int) (*model.Webhook, error) {
+	return &model.Webhook{ID: id, URL: "http://example.com"}, nil
+}
+
+func (s *webhookStoreMock) RecordAttempt(ctx context.Context, delivery *model.WebhookDelivery, attempt *model.WebhookAttempt) error {
+	s.attempts = append(s.attempts, attempt)
+	return nil
+}
+
+type webhookSenderMock struct {
+	code int
+	err  error
+}
+
+func (s *webhookSenderMock) Send(ctx context.Context, webhook *model.Webhook, delivery *model.WebhookDelivery) (int, error) {
+	return s.code, s.err
+}
+
+func TestWebhookDelivererDeliver(t *testing.T) {
+	errRejected := errors.New("rejected")
+
+	tests := []struct {
+		name     string
+		code     int
+		err      error
+		attempts int
+		status   string
+		// retry is a pause before the next attempt, zero when delivery is finished
+		retry time.Duration
+	}{
+		{"accepted", http.StatusOK, nil, 0, model.DeliverySucceeded, 0},
+		{"accepted after retries", http.StatusOK, nil, 3, model.DeliverySucceeded, 0},
+		{"server error", http.StatusInternalServerError, errRejected, 0, model.DeliveryPending, time.Minute},
+		{"server error backoff", http.StatusServiceUnavailable, errRejected, 2, model.DeliveryPending, 4 * time.Minute},
+		{"backoff is limited", http.StatusBadGateway, errRejected, 4, model.DeliveryPending, 10 * time.Minute},
+		{"too many requests", http.StatusTooManyRequests, errRejected, 0, model.DeliveryPending, time.Minute},
+		{"request timeout", http.StatusRequestTimeout, errRejected, 0, model.DeliveryPending, time.Minute},
+		{"unreachable", 0, errRejected, 0, model.DeliveryPending, time.Minute},
+		{"attempts are exhausted", http.StatusInternalServerError, errRejected, 5, model.DeliveryFailed, 0},
+		{"client error", http.StatusBadRequest, errRejected, 0, model.DeliveryFailed, 0},
+		{"gone", http.StatusGone, errRejected, 0, model.DeliveryFailed, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &webhookStoreMock{}
+			d := &WebhookDeliverer{
+				webhookStore: store,
+				sender:       &webhookSenderMock{code: tt.code, err: tt.err},
+				maxAttempts:  6,
+				retryBase:    time.Minute,
+				retryMax:     10 * time.Minute,
+			}
+
+			now := time.Now()
+			delivery := &model.WebhookDelivery{
+				ID:            1,
+				WebhookID:     2,
+				Status:        model.DeliveryPending,
+				Attempts:      tt.attempts,
+				NextAttemptAt: &now,
+			}
+
+			d.deliver(context.Background(), delivery)
+
+			if len(store.attempts) != 1 {
+				t.Fatalf("expected single recorded attempt, got %d", len(store.attempts))
+			}
+
+			attempt := store.attempts[0]
+			if attempt.Attempt != tt.attempts+1 || attempt.StatusCode != tt.code || attempt.DeliveryID != 1 {
+				t.Fatalf("unexpected attempt %+v", attempt)
+			}
+
+			if (tt.err == nil) != (attempt.Error == "") {
+				t.Fatalf("unexpected attempt error %q", attempt.Error)
+			}
+
+			if delivery.Status != tt.status || delivery.Attempts != tt.attempts+1 {
+				t.Fatalf("expected %s delivery after %d attempts, got %s after %d",
+					tt.status, tt.attempts+1, delivery.Status, delivery.Attempts)
+			}
+
+			if tt.retry == 0 {
+				if delivery.NextAttemptAt != nil {
+					t.Fatalf("expected finished delivery, got next attempt at %s", delivery.NextAttemptAt)
+				}
+				return
+			}
+
+			if delivery.NextAttemptAt == nil {
+				t.Fatal("expected next attempt")
+			}
+
+			retry := delivery.NextAttemptAt.Sub(now)
+			if retry < tt.retry || retry > tt.retry+time.Second {
+				t.Fatalf("expected retry in %s, got %s", tt.retry, retry)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.uber.org/fx"
+)
+
+// appendWorker runs worker in background while application is started.
+// Worker's context is canceled on application stop, stop waits for worker to return.
+func appendWorker(lc fx.Lifecycle, name string, worker func(ctx context.Context)) {
+	var (
+		cancel context.CancelFunc
+		done   chan struct{}
+	)
+
+	lc.Append(
+		fx.Hook{
+			OnStart: func(context.Context) error {
+				var ctx context.Context
+				ctx, cancel = context.WithCancel(context.Background())
+				done = make(chan struct{})
+
+				go func() {
+					defer close(done)
+					worker(ctx)
+				}()
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				log.Info().Msgf("stopping %s", name)
+				cancel()
+
+				select {
+				case <-done:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			},
+		},
+	)
+}
+
+// appendTicker runs tick in background right after application start and then every interval
+// until application stops.
+func appendTicker(lc fx.Lifecycle, name string, interval time.Duration, tick func(ctx context.Context)) {
+	appendWorker(lc, name, func(ctx context.Context) {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			tick(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+		}
+	})
+}
+
+// processBatches calls batch until it processes less than batchSize items, fails or ctx is done.
+// Batch returns number of items it claimed, its error is logged with msg unless ctx is done.
+//
+// Items are claimed by store for lease time, so workers processing batches may run in several
+// service instances. Items left unprocessed because ctx is done are claimed again after their
+// lease expires, so batch must not record result of processing interrupted by ctx.
+func processBatches(ctx context.Context, batchSize int, msg string, batch func(ctx context.Context) (int, error)) {
+	for ctx.Err() == nil {
+		n, err := batch(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Error().Err(err).Msg(msg)
+			}
+			return
+		}
+
+		if n < batchSize {
+			return
+		}
+	}
+}
+
+// backoff returns pause after attempt, pause starts from base and doubles with every attempt up to max.
+func backoff(base, max time.Duration, attempt int) time.Duration {
+	pause := base
+	for i := 1; i < attempt && pause < max; i++ {
+		pause *= 2
+	}
+
+	if pause > max {
+		pause = max
+	}
+
+	return pause
+}
+
+// inParallel calls fn for every index below n concurrently and waits for all calls to return.
+func inParallel(n int, fn func(i int)) {
+	var wg sync.WaitGroup
+	wg.Add(n)
+
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer wg.Done()
+			fn(i)
+		}(i)
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/fx"
+)
+
+// lifecycleMock collects hooks instead of running them.
+type lifecycleMock struct {
+	hooks []fx.Hook
+}
+
+func (l *lifecycleMock) Append(hook fx.Hook) {
+	l.hooks = append(l.hooks, hook)
+}
+
+func TestAppendTicker(t *testing.T) {
+	lc := &lifecycleMock{}
+	ticks := make(chan struct{}, 10)
+
+	appendTicker(lc, "test ticker", time.Hour, func(ctx context.Context) {
+		ticks <- struct{}{}
+	})
+
+	if len(lc.hooks) != 1 {
+		t.Fatalf("expected single hook, got %d", len(lc.hooks))
+	}
+
+	err := lc.hooks[0].OnStart(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-ticks:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected tick right after start")
+	}
+
+	err = lc.hooks[0].OnStop(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ticks) != 0 {
+		t.Fatalf("expected no ticks before interval, got %d", len(ticks))
+	}
+}
+
+func TestProcessBatches(t *testing.T) {
+	errClaim := errors.New("claim failed")
+
+	tests := []struct {
+		name    string
+		batches []int
+		err     error
+		// calls is an expected number of batch calls
+		calls int
+	}{
+		{"empty", []int{0}, nil, 1},
+		{"short batch", []int{3}, nil, 1},
+		{"full batches", []int{5, 5, 2}, nil, 3},
+		{"full batches then empty", []int{5, 5, 0}, nil, 3},
+		{"failure", []int{5, 5}, errClaim, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+
+			processBatches(context.Background(), 5, "can not process batch", func(ctx context.Context) (int, error) {
+				calls++
+				if calls > len(tt.batches) {
+					return 0, tt.err
+				}
+
+				return tt.batches[calls-1], nil
+			})
+
+			if calls != tt.calls {
+				t.Fatalf("expected %d batches, got %d", tt.calls, calls)
+			}
+		})
+	}
+}
+
+func TestProcessBatchesCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := 0
+	processBatches(ctx, 5, "can not process batch", func(ctx context.Context) (int, error) {
+		calls++
+		cancel()
+		return 5, nil
+	})
+
+	if calls != 1 {
+		t.Fatalf("expected processing to stop after cancel, got %d batches", calls)
+	}
+}
+
+func TestInParallel(t *testing.T) {
+	var (
+		sum     int64
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		inParallel(3, func(i int) {
+			started <- struct{}{}
+			<-release
+			atomic.AddInt64(&sum, int64(i))
+		})
+	}()
+
+	// All calls must be running at once before any of them returns
+	for i := 0; i < 3; i++ {
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected calls to run concurrently")
+		}
+	}
+	close(release)
+
+	<-done
+
+	if sum != 0+1+2 {
+		t.Fatalf("expected every index to be processed, got sum %d", sum)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	expected := []time.Duration{
+		30 * time.Second,
+		time.Minute,
+		2 * time.Minute,
+		4 * time.Minute,
+		5 * time.Minute,
+		5 * time.Minute,
+	}
+
+	for i, pause := range expected {
+		if b := backoff(30*time.Second, 5*time.Minute, i+1); b != pause {
+			t.Fatalf("expected pause %s after attempt %d, got %s", pause, i+1, b)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+// Package cron parses standard five-field cron expressions
+// ("minute hour day-of-month month day-of-week") and computes activation times.
+package cron
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Schedule is a parsed cron expression.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// Day of month and day of week are ORed when both are restricted, as in classic cron.
+	domStar, dowStar bool
+}
+
+type bounds struct {
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	minutes = bounds{0, 59, nil}
+	hours   = bounds{0, 23, nil}
+	doms    = bounds{1, 31, nil}
+	months  = bounds{1, 12, map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dows = bounds{0, 6, map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses cron expression, descriptors like @daily are supported as well.
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if d, ok := descriptors[strings.ToLower(expr)]; ok {
+		expr = d
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, errors.Errorf("cron expression %q must have 5 fields", expr)
+	}
+
+	s := &Schedule{
+		domStar: fields[2] == "*" || fields[2] == "?",
+		dowStar: fields[4] == "*" || fields[4] == "?",
+	}
+
+	var err error
+	for _, f := range []struct {
+		dst *uint64
+		src string
+		b   bounds
+	}{
+		{&s.minute, fields[0], minutes},
+		{&s.hour, fields[1], hours},
+		{&s.dom, fields[2], doms},
+		{&s.month, fields[3], months},
+		{&s.dow, fields[4], dows},
+	} {
+		*f.dst, err = parseField(f.src, f.b)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing cron expression %q", expr)
+		}
+	}
+
+	return s, nil
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+	var bitset uint64
+
+	for _, part := range strings.Split(field, ",") {
+		bs, err := parseRange(part, b)
+		if err != nil {
+			return 0, err
+		}
+		bitset |= bs
+	}
+
+	return bitset, nil
+}
+
+// parseRange parses "*", "?", "n", "a-b" each optionally followed by "/step".
+func parseRange(part string, b bounds) (uint64, error) {
+	rangeAndStep := strings.SplitN(part, "/", 2)
+	lowAndHigh := strings.SplitN(rangeAndStep[0], "-", 2)
+
+	var (
+		start, end uint
+		err        error
+	)
+
+	if lowAndHigh[0] == "*" || lowAndHigh[0] == "?" {
+		if len(lowAndHigh) > 1 {
+			return 0, errors.Errorf("invalid range %q", part)
+		}
+		start, end = b.min, b.max
+	} else {
+		start, err = parseValue(lowAndHigh[0], b)
+		if err != nil {
+			return 0, err
+		}
+
+		end = start
+		if len(lowAndHigh) > 1 {
+			end, err = parseValue(lowAndHigh[1], b)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	step := uint(1)
+	if len(rangeAndStep) > 1 {
+		s, err := strconv.ParseUint(rangeAndStep[1], 10, 8)
+		if err != nil || s == 0 {
+			return 0, errors.Errorf("invalid step in %q", part)
+		}
+		step = uint(s)
+
+		// "n/step" means from n till the end of range
+		if len(lowAndHigh) == 1 && lowAndHigh[0] != "*" && lowAndHigh[0] != "?" {
+			end = b.max
+		}
+	}
+
+	if start > end {
+		return 0, errors.Errorf("invalid range %q", part)
+	}
+
+	var bitset uint64
+	for i := start; i <= end; i += step {
+		bitset |= 1 << i
+	}
+
+	return bitset, nil
+}
+
+func parseValue(v string, b bounds) (uint, error) {
+	if n, ok := b.names[strings.ToLower(v)]; ok {
+		return n, nil
+	}
+
+	n, err := strconv.ParseUint(v, 10, 8)
+	if err != nil {
+		return 0, errors.Errorf("invalid value %q", v)
+	}
+
+	// Sunday may be written as 7
+	if b.max == dows.max && n == 7 {
+		n = 0
+	}
+
+	if uint(n) < b.min || uint(n) > b.max {
+		return 0, errors.Errorf("value %d is out of range [%d, %d]", n, b.min, b.max)
+	}
+
+	return uint(n), nil
+}
+
+// Next returns the first activation time after t in t's location,
+// zero time is returned if schedule can't be satisfied within five years.
+// Local times skipped by DST transition are not activated,
+// times repeated by DST transition are activated once.
+func (s *Schedule) Next(t time.Time) time.Time {
+	for {
+		t = s.next(t)
+		if t.IsZero() || !repeated(t) {
+			return t
+		}
+	}
+}
+
+// repeated reports whether wall clock of t has already occurred an hour ago
+// which happens when clocks are turned back.
+func repeated(t time.Time) bool {
+	prev := t.Add(-time.Hour)
+	return prev.Day() == t.Day() && prev.Hour() == t.Hour() && prev.Minute() == t.Minute()
+}
+
+func (s *Schedule) next(t time.Time) time.Time {
+	loc := t.Location()
+
+	// Start from the next whole minute
+	t = t.Add(time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+
+	// Once a field is advanced lower fields are reset to their minimum
+	added := false
+
+	yearLimit := t.Year() + 5
+
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for 1<<uint(t.Month())&s.month == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 1, 0)
+
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+
+	for !s.dayMatches(t) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 0, 1)
+
+		// Midnight may not exist or be shifted due to DST transition
+		if t.Hour() != 0 {
+			if t.Hour() > 12 {
+				t = t.Add(time.Duration(24-t.Hour()) * time.Hour)
+			} else {
+				t = t.Add(time.Duration(-t.Hour()) * time.Hour)
+			}
+		}
+
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+
+	for 1<<uint(t.Hour())&s.hour == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+		}
+		t = t.Add(time.Hour)
+
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+
+	for 1<<uint(t.Minute())&s.minute == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Minute)
+		}
+		t = t.Add(time.Minute)
+
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+
+	return t
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := 1<<uint(t.Day())&s.dom > 0
+	dowMatch := 1<<uint(t.Weekday())&s.dow > 0
+
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
@@ -0,0 +1,116 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*-5 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"a * * * *",
+		"* * * foo *",
+		"@every",
+	}
+
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := Parse(expr); err == nil {
+				t.Fatalf("expected error parsing %q", expr)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		timezone string
+		after    string
+		// next is empty when schedule can't be satisfied
+		next string
+	}{
+		{"every minute", "* * * * *", "UTC", "2026-01-01T10:07:30Z", "2026-01-01T10:08:00Z"},
+		{"step", "*/15 * * * *", "UTC", "2026-01-01T10:07:30Z", "2026-01-01T10:15:00Z"},
+		{"step is exclusive", "*/15 * * * *", "UTC", "2026-01-01T10:15:00Z", "2026-01-01T10:30:00Z"},
+		{"step from value", "5/20 * * * *", "UTC", "2026-01-01T10:26:00Z", "2026-01-01T10:45:00Z"},
+		{"range with step", "10-20/5 * * * *", "UTC", "2026-01-01T10:12:00Z", "2026-01-01T10:15:00Z"},
+		{"range with step wraps hour", "10-20/5 * * * *", "UTC", "2026-01-01T10:20:00Z", "2026-01-01T11:10:00Z"},
+		{"list", "0 8,12,18 * * *", "UTC", "2026-01-01T12:00:00Z", "2026-01-01T18:00:00Z"},
+		{"list wraps day", "0 8,12,18 * * *", "UTC", "2026-01-01T18:00:00Z", "2026-01-02T08:00:00Z"},
+		{"weekdays", "0 9 * * mon-fri", "UTC", "2026-01-02T09:00:00Z", "2026-01-05T09:00:00Z"},
+		{"sunday as 0", "0 0 * * 0", "UTC", "2026-01-01T00:00:00Z", "2026-01-04T00:00:00Z"},
+		{"sunday as 7", "0 0 * * 7", "UTC", "2026-01-01T00:00:00Z", "2026-01-04T00:00:00Z"},
+		{"day of month", "0 0 31 * *", "UTC", "2026-02-01T00:00:00Z", "2026-03-31T00:00:00Z"},
+		{"day of month or week, week first", "0 0 13 * fri", "UTC", "2026-01-01T00:00:00Z", "2026-01-02T00:00:00Z"},
+		{"day of month or week, month first", "0 0 13 * fri", "UTC", "2026-01-10T00:00:00Z", "2026-01-13T00:00:00Z"},
+		{"day of month and any weekday", "0 0 13 * *", "UTC", "2026-01-01T00:00:00Z", "2026-01-13T00:00:00Z"},
+		{"month names", "0 0 1 jan,jul *", "UTC", "2026-02-01T00:00:00Z", "2026-07-01T00:00:00Z"},
+		{"leap day", "0 0 29 2 *", "UTC", "2026-01-01T00:00:00Z", "2028-02-29T00:00:00Z"},
+		{"never", "0 0 30 2 *", "UTC", "2026-01-01T00:00:00Z", ""},
+		{"descriptor", "@yearly", "UTC", "2026-06-01T00:00:00Z", "2027-01-01T00:00:00Z"},
+		{"descriptor is case insensitive", "@Daily", "UTC", "2026-06-01T00:00:00Z", "2026-06-02T00:00:00Z"},
+		{"location", "0 9 * * *", "Asia/Tokyo", "2026-05-31T23:00:00Z", "2026-06-01T09:00:00+09:00"},
+		{"dst gap is skipped", "30 2 * * *", "America/New_York", "2026-03-07T03:00:00-05:00", "2026-03-09T02:30:00-04:00"},
+		{"dst gap hourly", "0 * * * *", "America/New_York", "2026-03-08T01:30:00-05:00", "2026-03-08T03:00:00-04:00"},
+		{"dst gap at midnight", "0 0 * * *", "America/Santiago", "2026-09-05T12:00:00-04:00", "2026-09-07T00:00:00-03:00"},
+		{"dst repeat first pass", "30 1 * * *", "America/New_York", "2026-11-01T00:00:00-04:00", "2026-11-01T01:30:00-04:00"},
+		{"dst repeat is activated once", "30 1 * * *", "America/New_York", "2026-11-01T01:30:00-04:00", "2026-11-02T01:30:00-05:00"},
+		{"dst repeat half hourly", "*/30 * * * *", "America/New_York", "2026-11-01T01:30:00-04:00", "2026-11-01T02:00:00-05:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			loc, err := time.LoadLocation(tt.timezone)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			next := s.Next(parseTime(t, tt.after).In(loc))
+
+			if tt.next == "" {
+				if !next.IsZero() {
+					t.Fatalf("expected no activation, got %s", next)
+				}
+				return
+			}
+
+			if expected := parseTime(t, tt.next); !next.Equal(expected) {
+				t.Fatalf("expected %s, got %s", expected, next)
+			}
+
+			if next.Location() != loc {
+				t.Fatalf("expected activation in %s, got %s", loc, next.Location())
+			}
+		})
+	}
+}
+
+func parseTime(t *testing.T, s string) time.Time {
+	t.Helper()
+
+	v, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return v
+}
@@ -0,0 +1,19 @@
+package dataprovider
+
+import (
+	"context"
+	"time"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+type DeliveryStore interface {
+	// Insert inserts pending deliveries, deliveries that already exist are skipped.
+	Insert(ctx context.Context, deliveries []*model.Delivery) error
+	ListByNotification(ctx context.Context, notificationID int) ([]*model.Delivery, error)
+	// ClaimDue claims up to limit pending deliveries which attempt time has come,
+	// claimed deliveries are not claimed again until lease expires.
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*model.Delivery, error)
+	// Update stores delivery's status, attempts counter, error and next attempt time.
+	Update(ctx context.Context, delivery *model.Delivery) error
+}
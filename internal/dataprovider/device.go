@@ -0,0 +1,16 @@
+package dataprovider
+
+import (
+	"context"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+type DeviceStore interface {
+	// Register stores device, already registered token is moved to device's user.
+	Register(ctx context.Context, device *model.Device) error
+	Unregister(ctx context.Context, userID int64, id int) error
+	ListByUser(ctx context.Context, userID int64) ([]*model.Device, error)
+	// DeleteTokens deletes devices with tokens rejected by platform.
+	DeleteTokens(ctx context.Context, platform string, tokens []string) error
+}
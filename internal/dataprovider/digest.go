@@ -0,0 +1,27 @@
+package dataprovider
+
+import (
+	"context"
+	"time"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+type DigestStore interface {
+	// Get returns nil when user has no digest.
+	Get(ctx context.Context, userID int64) (*model.Digest, error)
+	// Set inserts or replaces user's digest, deliveries collected for other channel are released.
+	Set(ctx context.Context, digest *model.Digest) error
+	// Delete deletes user's digest and releases its collected deliveries.
+	// Released deliveries are sent one by one.
+	Delete(ctx context.Context, userID int64) error
+	// ClaimDue claims up to limit digests which time has come,
+	// claimed digests are not claimed again until lease expires.
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*model.Digest, error)
+	// Reschedule sets time of user's next digest.
+	Reschedule(ctx context.Context, userID int64, nextAt time.Time) error
+	// ListCollected returns up to limit deliveries collected to user's digest in order of collection.
+	ListCollected(ctx context.Context, userID int64, channel string, limit int) ([]*model.Delivery, error)
+	// MarkDigested marks collected deliveries as sent within digest at time.
+	MarkDigested(ctx context.Context, ids []int, at time.Time) error
+}
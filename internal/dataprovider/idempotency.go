@@ -0,0 +1,19 @@
+package dataprovider
+
+import (
+	"context"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+type IdempotencyStore interface {
+	// Claim stores in-flight key unless the same live key exists, expired key is replaced.
+	// It reports whether key is claimed.
+	Claim(ctx context.Context, key *model.IdempotencyKey) (bool, error)
+	Get(ctx context.Context, userID int64, key string) (*model.IdempotencyKey, error)
+	// Complete stores key's response snapshot and expiration time.
+	Complete(ctx context.Context, key *model.IdempotencyKey) error
+	Delete(ctx context.Context, userID int64, key string) error
+	// DeleteExpired deletes expired keys, number of deleted keys is returned.
+	DeleteExpired(ctx context.Context) (int, error)
+}
@@ -0,0 +1,13 @@
+package dataprovider
+
+import (
+	"context"
+)
+
+// NotificationListener receives ids of notifications published by any service instance.
+type NotificationListener interface {
+	// Listen calls ready once it starts receiving notifications and then calls published
+	// for every published notification. Error returned by callbacks stops listening.
+	// Listen blocks until ctx is done or connection to database is lost.
+	Listen(ctx context.Context, ready func() error, published func(id int) error) error
+}
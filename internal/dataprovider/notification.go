@@ -3,9 +3,38 @@ package dataprovider
 import (
 	"context"
 
+	"github.com/pkg/errors"
+
 	"github.com/hummerd/gophercon/internal/model"
 )
 
+var (
+	// ErrNotFound is returned when requested entity does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrVersionConflict is returned when entity was changed concurrently.
+	ErrVersionConflict = errors.New("version conflict")
+	// ErrAlreadyExists is returned when entity with the same key already exists.
+	ErrAlreadyExists = errors.New("already exists")
+)
+
 type NotificationStore interface {
 	Insert(ctx context.Context, notification *model.Notification) error
+	InsertBatch(ctx context.Context, notifications []*model.Notification) error
+	Get(ctx context.Context, id int) (*model.Notification, error)
+	Update(ctx context.Context, notification *model.Notification) error
+	Revoke(ctx context.Context, id int) error
+	GetByUser(ctx context.Context, user *model.User, filter *model.InboxFilter) ([]*model.Notification, error)
+	// Search returns page of notifications visible to user matching full-text query ordered by rank.
+	Search(ctx context.Context, user *model.User, filter *model.SearchFilter) ([]*model.SearchResult, error)
+	GetPublishedAfter(ctx context.Context, user *model.User, pos *model.StreamPosition, limit int) ([]*model.Notification, error)
+	FindPublishedAfter(ctx context.Context, pos *model.StreamPosition, limit int) ([]*model.Notification, error)
+	// LastStreamPosition returns position of the latest published notification.
+	LastStreamPosition(ctx context.Context) (*model.StreamPosition, error)
+	Find(ctx context.Context, filter *model.NotificationFilter) ([]*model.Notification, error)
+	MarkRead(ctx context.Context, user *model.User, id int) error
+	MarkAllRead(ctx context.Context, user *model.User) error
+	CountUnread(ctx context.Context, user *model.User) (int, error)
+	// PublishDue publishes up to limit scheduled notifications which publishing time has come,
+	// events of published notifications are stored to outbox in the same transaction.
+	PublishDue(ctx context.Context, limit int) (int, error)
 }
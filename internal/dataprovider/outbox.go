@@ -0,0 +1,25 @@
+package dataprovider
+
+import (
+	"context"
+	"time"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+// OutboxStore provides access to outbox entries, entries are created by NotificationStore
+// in the same transaction as notification changes.
+type OutboxStore interface {
+	Get(ctx context.Context, id int64) (*model.OutboxEntry, error)
+	List(ctx context.Context, filter *model.OutboxFilter) ([]*model.OutboxEntry, error)
+	Stats(ctx context.Context) (*model.OutboxStats, error)
+	// ClaimDue claims up to limit pending entries which attempt time has come in order of creation,
+	// claimed entries are not claimed again until lease expires.
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*model.OutboxEntry, error)
+	// Update stores entry's status, attempts counter, error, next attempt and publishing time.
+	Update(ctx context.Context, entry *model.OutboxEntry) error
+	// Replay makes entry pending again with attempts counter reset.
+	Replay(ctx context.Context, id int64) error
+	// ReplayFailed makes all failed entries pending again, number of replayed entries is returned.
+	ReplayFailed(ctx context.Context) (int, error)
+}
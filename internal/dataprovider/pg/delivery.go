@@ -0,0 +1,161 @@
+package pg
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/dataprovider"
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+var channelDeliveryColumns = []string{
+	"id",
+	"notification_id",
+	"user_id",
+	"channel",
+	"status",
+	"attempts",
+	"error",
+	"next_attempt_at",
+	"sent_at",
+	"digested_at",
+	"created_at",
+}
+
+func NewDeliveryStore(db sqlx.ExtContext) *DeliveryStore {
+	return &DeliveryStore{
+		db: db,
+	}
+}
+
+// DeliveryStore is a channel deliveries postgres store
+type DeliveryStore struct {
+	db sqlx.ExtContext
+}
+
+// Insert inserts pending deliveries, existing deliveries are left untouched
+func (s *DeliveryStore) Insert(ctx context.Context, deliveries []*model.Delivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	insert := sq.Insert("app.notification_deliveries").
+		Columns("notification_id", "user_id", "channel", "status", "next_attempt_at")
+
+	for _, d := range deliveries {
+		insert = insert.Values(d.NotificationID, d.UserID, d.Channel, d.Status, d.NextAttemptAt)
+	}
+
+	query, args, err := insert.
+		Suffix("ON CONFLICT (notification_id, user_id, channel) DO NOTHING").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for inserting deliveries")
+	}
+
+	_, err = s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrap(err, "inserting deliveries")
+	}
+
+	return nil
+}
+
+// ListByNotification gets all deliveries of notification
+func (s *DeliveryStore) ListByNotification(ctx context.Context, notificationID int) ([]*model.Delivery, error) {
+	deliveries := make([]*model.Delivery, 0)
+
+	query, args, err := sq.Select(channelDeliveryColumns...).
+		From("app.notification_deliveries").
+		Where(sq.Eq{"notification_id": notificationID}).
+		OrderBy("id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for listing deliveries")
+	}
+
+	err = sqlx.SelectContext(ctx, s.db, &deliveries, query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "selecting deliveries from database with query %s", query)
+	}
+
+	return deliveries, nil
+}
+
+// ClaimDue claims due pending deliveries by moving their next attempt time to the end of lease.
+// Rows are locked with SKIP LOCKED so several instances may deliver concurrently.
+func (s *DeliveryStore) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*model.Delivery, error) {
+	deliveries := make([]*model.Delivery, 0, limit)
+
+	now := time.Now()
+
+	due, dueArgs, err := sq.Select("id").
+		From("app.notification_deliveries").
+		Where(sq.Eq{"status": model.DeliveryPending}).
+		Where(sq.LtOrEq{"next_attempt_at": now}).
+		OrderBy("next_attempt_at", "id").
+		Limit(uint64(limit)).
+		Suffix("FOR UPDATE SKIP LOCKED").
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for selecting due deliveries")
+	}
+
+	query, args, err := sq.Update("app.notification_deliveries").
+		Set("next_attempt_at", now.Add(lease)).
+		Where(sq.Expr("id IN ("+due+")", dueArgs...)).
+		Suffix("returning " + strings.Join(channelDeliveryColumns, ", ")).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for claiming due deliveries")
+	}
+
+	err = sqlx.SelectContext(ctx, s.db, &deliveries, query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "claiming due deliveries with query %s", query)
+	}
+
+	return deliveries, nil
+}
+
+// Update updates delivery's status, attempts, error and next attempt time
+func (s *DeliveryStore) Update(ctx context.Context, delivery *model.Delivery) error {
+	query, args, err := sq.Update("app.notification_deliveries").
+		SetMap(map[string]interface{}{
+			"status":          delivery.Status,
+			"attempts":        delivery.Attempts,
+			"error":           delivery.Error,
+			"next_attempt_at": delivery.NextAttemptAt,
+			"sent_at":         delivery.SentAt,
+		}).
+		Where(sq.Eq{"id": delivery.ID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for updating delivery")
+	}
+
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrapf(err, "updating delivery %d", delivery.ID)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrapf(err, "updating delivery %d", delivery.ID)
+	}
+
+	if n == 0 {
+		return dataprovider.ErrNotFound
+	}
+
+	return nil
+}
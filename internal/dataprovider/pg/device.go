@@ -0,0 +1,127 @@
+package pg
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/dataprovider"
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+var deviceColumns = []string{
+	"id",
+	"user_id",
+	"platform",
+	"token",
+	"created_at",
+	"updated_at",
+}
+
+func NewDeviceStore(db sqlx.ExtContext) *DeviceStore {
+	return &DeviceStore{
+		db: db,
+	}
+}
+
+// DeviceStore is a push devices postgres store
+type DeviceStore struct {
+	db sqlx.ExtContext
+}
+
+// Register inserts device or moves existing token to device's user
+func (s *DeviceStore) Register(ctx context.Context, device *model.Device) error {
+	query, args, err := sq.Insert("app.devices").
+		SetMap(map[string]interface{}{
+			"user_id":  device.UserID,
+			"platform": device.Platform,
+			"token":    device.Token,
+		}).
+		Suffix("ON CONFLICT (platform, token) DO UPDATE SET user_id = excluded.user_id, updated_at = now() " +
+			"returning id, created_at, updated_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for registering device")
+	}
+
+	err = s.db.QueryRowxContext(ctx, query, args...).Scan(&device.ID, &device.CreatedAt, &device.UpdatedAt)
+	if err != nil {
+		return errors.Wrap(err, "can't scan device id")
+	}
+
+	return nil
+}
+
+// Unregister deletes user's device
+func (s *DeviceStore) Unregister(ctx context.Context, userID int64, id int) error {
+	query, args, err := sq.Delete("app.devices").
+		Where(sq.Eq{"id": id, "user_id": userID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for unregistering device")
+	}
+
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrapf(err, "unregistering device %d", id)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrapf(err, "unregistering device %d", id)
+	}
+
+	if n == 0 {
+		return dataprovider.ErrNotFound
+	}
+
+	return nil
+}
+
+// ListByUser gets all devices of user
+func (s *DeviceStore) ListByUser(ctx context.Context, userID int64) ([]*model.Device, error) {
+	devices := make([]*model.Device, 0)
+
+	query, args, err := sq.Select(deviceColumns...).
+		From("app.devices").
+		Where(sq.Eq{"user_id": userID}).
+		OrderBy("id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for listing devices")
+	}
+
+	err = sqlx.SelectContext(ctx, s.db, &devices, query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "selecting devices from database with query %s", query)
+	}
+
+	return devices, nil
+}
+
+// DeleteTokens deletes devices of platform with tokens
+func (s *DeviceStore) DeleteTokens(ctx context.Context, platform string, tokens []string) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	query, args, err := sq.Delete("app.devices").
+		Where(sq.Eq{"platform": platform, "token": tokens}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for deleting device tokens")
+	}
+
+	_, err = s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrapf(err, "deleting %d device tokens", len(tokens))
+	}
+
+	return nil
+}
@@ -0,0 +1,248 @@
+package pg
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/dataprovider"
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+var digestColumns = []string{
+	"user_id",
+	"frequency",
+	"channel",
+	"max_priority",
+	"timezone",
+	"next_at",
+}
+
+func NewDigestStore(db sqlx.ExtContext) *DigestStore {
+	return &DigestStore{
+		db: db,
+	}
+}
+
+// DigestStore is a digests postgres store, it also manages deliveries collected to digests
+type DigestStore struct {
+	db sqlx.ExtContext
+}
+
+// Get gets user's digest
+func (s *DigestStore) Get(ctx context.Context, userID int64) (*model.Digest, error) {
+	query, args, err := sq.Select(digestColumns...).
+		From("app.digests").
+		Where(sq.Eq{"user_id": userID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for getting digest")
+	}
+
+	digests := make([]*model.Digest, 0, 1)
+
+	err = sqlx.SelectContext(ctx, s.db, &digests, query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "selecting digest with query %s", query)
+	}
+
+	if len(digests) == 0 {
+		return nil, nil
+	}
+
+	return digests[0], nil
+}
+
+// Set inserts or replaces user's digest and releases deliveries collected for other channel in single transaction
+func (s *DigestStore) Set(ctx context.Context, digest *model.Digest) error {
+	return withTx(ctx, s.db, func(tx sqlx.ExtContext) error {
+		query, args, err := sq.Insert("app.digests").
+			SetMap(map[string]interface{}{
+				"user_id":      digest.UserID,
+				"frequency":    digest.Frequency,
+				"channel":      digest.Channel,
+				"max_priority": digest.MaxPriority,
+				"timezone":     digest.Timezone,
+				"next_at":      digest.NextAt,
+			}).
+			Suffix("ON CONFLICT (user_id) DO UPDATE SET " +
+				"frequency = excluded.frequency, channel = excluded.channel, " +
+				"max_priority = excluded.max_priority, timezone = excluded.timezone, " +
+				"next_at = excluded.next_at, updated_at = now()").
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			return errors.Wrap(err, "creating sql query for setting digest")
+		}
+
+		_, err = tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return errors.Wrapf(err, "setting digest of user %d", digest.UserID)
+		}
+
+		return releaseCollected(ctx, tx, digest.UserID, sq.NotEq{"channel": digest.Channel})
+	})
+}
+
+// Delete deletes user's digest and releases its collected deliveries in single transaction,
+// deleting missing digest is not an error
+func (s *DigestStore) Delete(ctx context.Context, userID int64) error {
+	return withTx(ctx, s.db, func(tx sqlx.ExtContext) error {
+		query, args, err := sq.Delete("app.digests").
+			Where(sq.Eq{"user_id": userID}).
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			return errors.Wrap(err, "creating sql query for deleting digest")
+		}
+
+		_, err = tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return errors.Wrapf(err, "deleting digest of user %d", userID)
+		}
+
+		return releaseCollected(ctx, tx, userID, sq.Eq{})
+	})
+}
+
+// releaseCollected makes user's collected deliveries matching pred pending, so they are sent one by one
+func releaseCollected(ctx context.Context, db sqlx.ExtContext, userID int64, pred sq.Sqlizer) error {
+	query, args, err := sq.Update("app.notification_deliveries").
+		Set("status", model.DeliveryPending).
+		Set("next_attempt_at", time.Now()).
+		Where(sq.Eq{"user_id": userID, "status": model.DeliveryCollected}).
+		Where(pred).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for releasing collected deliveries")
+	}
+
+	_, err = db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrapf(err, "releasing collected deliveries of user %d", userID)
+	}
+
+	return nil
+}
+
+// ClaimDue claims due digests by moving their next time to the end of lease.
+// Rows are locked with SKIP LOCKED so several instances may send digests concurrently.
+func (s *DigestStore) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*model.Digest, error) {
+	digests := make([]*model.Digest, 0, limit)
+
+	now := time.Now()
+
+	due, dueArgs, err := sq.Select("user_id").
+		From("app.digests").
+		Where(sq.LtOrEq{"next_at": now}).
+		OrderBy("next_at", "user_id").
+		Limit(uint64(limit)).
+		Suffix("FOR UPDATE SKIP LOCKED").
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for selecting due digests")
+	}
+
+	query, args, err := sq.Update("app.digests").
+		Set("next_at", now.Add(lease)).
+		Where(sq.Expr("user_id IN ("+due+")", dueArgs...)).
+		Suffix("returning " + strings.Join(digestColumns, ", ")).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for claiming due digests")
+	}
+
+	err = sqlx.SelectContext(ctx, s.db, &digests, query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "claiming due digests with query %s", query)
+	}
+
+	return digests, nil
+}
+
+// Reschedule updates time of user's next digest
+func (s *DigestStore) Reschedule(ctx context.Context, userID int64, nextAt time.Time) error {
+	query, args, err := sq.Update("app.digests").
+		Set("next_at", nextAt).
+		Where(sq.Eq{"user_id": userID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for rescheduling digest")
+	}
+
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrapf(err, "rescheduling digest of user %d", userID)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrapf(err, "rescheduling digest of user %d", userID)
+	}
+
+	if n == 0 {
+		return dataprovider.ErrNotFound
+	}
+
+	return nil
+}
+
+// ListCollected gets deliveries collected to user's digest through channel
+func (s *DigestStore) ListCollected(ctx context.Context, userID int64, channel string, limit int) ([]*model.Delivery, error) {
+	deliveries := make([]*model.Delivery, 0)
+
+	query, args, err := sq.Select(channelDeliveryColumns...).
+		From("app.notification_deliveries").
+		Where(sq.Eq{"user_id": userID, "channel": channel, "status": model.DeliveryCollected}).
+		OrderBy("id").
+		Limit(uint64(limit)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for listing collected deliveries")
+	}
+
+	err = sqlx.SelectContext(ctx, s.db, &deliveries, query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "selecting collected deliveries with query %s", query)
+	}
+
+	return deliveries, nil
+}
+
+// MarkDigested marks collected deliveries as succeeded within digest
+func (s *DigestStore) MarkDigested(ctx context.Context, ids []int, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query, args, err := sq.Update("app.notification_deliveries").
+		SetMap(map[string]interface{}{
+			"status":          model.DeliverySucceeded,
+			"attempts":        sq.Expr("attempts + 1"),
+			"next_attempt_at": nil,
+			"sent_at":         at,
+			"digested_at":     at,
+		}).
+		Where(sq.Eq{"id": ids, "status": model.DeliveryCollected}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for marking digested deliveries")
+	}
+
+	_, err = s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrapf(err, "marking %d deliveries as digested", len(ids))
+	}
+
+	return nil
+}
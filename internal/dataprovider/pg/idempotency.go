@@ -0,0 +1,183 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/dataprovider"
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+var idempotencyColumns = []string{
+	"user_id",
+	"key",
+	"request_hash",
+	"status",
+	"status_code",
+	"content_type",
+	"response",
+	"created_at",
+	"expires_at",
+}
+
+func NewIdempotencyStore(db sqlx.ExtContext) *IdempotencyStore {
+	return &IdempotencyStore{
+		db: db,
+	}
+}
+
+// IdempotencyStore is an idempotency keys postgres store
+type IdempotencyStore struct {
+	db sqlx.ExtContext
+}
+
+// Claim inserts in-flight key, existing key is replaced only if it is expired
+func (s *IdempotencyStore) Claim(ctx context.Context, key *model.IdempotencyKey) (bool, error) {
+	query, args, err := sq.Insert("app.idempotency_keys").
+		SetMap(map[string]interface{}{
+			"user_id":      key.UserID,
+			"key":          key.Key,
+			"request_hash": key.RequestHash,
+			"status":       model.IdempotencyInFlight,
+			"expires_at":   key.ExpiresAt,
+		}).
+		Suffix(`ON CONFLICT (user_id, key) DO UPDATE SET
+			request_hash = excluded.request_hash,
+			status = excluded.status,
+			status_code = 0,
+			content_type = '',
+			response = NULL,
+			created_at = now(),
+			expires_at = excluded.expires_at
+		WHERE app.idempotency_keys.expires_at <= now()
+		returning created_at`).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return false, errors.Wrap(err, "creating sql query for claiming idempotency key")
+	}
+
+	err = s.db.QueryRowxContext(ctx, query, args...).Scan(&key.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "claiming idempotency key %q", key.Key)
+	}
+
+	key.Status = model.IdempotencyInFlight
+
+	return true, nil
+}
+
+// Get gets live key, expired key is reported as not found
+func (s *IdempotencyStore) Get(ctx context.Context, userID int64, key string) (*model.IdempotencyKey, error) {
+	query, args, err := sq.Select(idempotencyColumns...).
+		From("app.idempotency_keys").
+		Where(sq.Eq{"user_id": userID, "key": key}).
+		Where(sq.Expr("expires_at > now()")).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for getting idempotency key")
+	}
+
+	keys := make([]*model.IdempotencyKey, 0, 1)
+
+	err = sqlx.SelectContext(ctx, s.db, &keys, query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "selecting idempotency key with query %s", query)
+	}
+
+	if len(keys) == 0 {
+		return nil, dataprovider.ErrNotFound
+	}
+
+	return keys[0], nil
+}
+
+// Complete stores response snapshot of in-flight key
+func (s *IdempotencyStore) Complete(ctx context.Context, key *model.IdempotencyKey) error {
+	query, args, err := sq.Update("app.idempotency_keys").
+		SetMap(map[string]interface{}{
+			"status":       model.IdempotencyCompleted,
+			"status_code":  key.StatusCode,
+			"content_type": key.ContentType,
+			"response":     key.Response,
+			"expires_at":   key.ExpiresAt,
+		}).
+		Where(sq.Eq{
+			"user_id":      key.UserID,
+			"key":          key.Key,
+			"request_hash": key.RequestHash,
+			"status":       model.IdempotencyInFlight,
+		}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for completing idempotency key")
+	}
+
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrapf(err, "completing idempotency key %q", key.Key)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrapf(err, "completing idempotency key %q", key.Key)
+	}
+
+	if n == 0 {
+		return dataprovider.ErrNotFound
+	}
+
+	key.Status = model.IdempotencyCompleted
+
+	return nil
+}
+
+// Delete deletes key
+func (s *IdempotencyStore) Delete(ctx context.Context, userID int64, key string) error {
+	query, args, err := sq.Delete("app.idempotency_keys").
+		Where(sq.Eq{"user_id": userID, "key": key}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for deleting idempotency key")
+	}
+
+	_, err = s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrapf(err, "deleting idempotency key %q", key)
+	}
+
+	return nil
+}
+
+// DeleteExpired deletes expired keys
+func (s *IdempotencyStore) DeleteExpired(ctx context.Context) (int, error) {
+	query, args, err := sq.Delete("app.idempotency_keys").
+		Where(sq.Expr("expires_at <= now()")).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return 0, errors.Wrap(err, "creating sql query for deleting expired idempotency keys")
+	}
+
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, errors.Wrap(err, "deleting expired idempotency keys")
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrap(err, "deleting expired idempotency keys")
+	}
+
+	return int(n), nil
+}
@@ -0,0 +1,157 @@
+package pg
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// publishedChannel is a channel notified by trigger on app.notifications
+const publishedChannel = "notifications_published"
+
+// NotifyConn is a dedicated database connection receiving asynchronous notifications.
+// database/sql does not expose notifications so it is implemented over driver's connection.
+type NotifyConn interface {
+	Listen(ctx context.Context, channel string) error
+	// WaitForNotification blocks until notification is received and returns its channel and payload.
+	WaitForNotification(ctx context.Context) (string, string, error)
+	Close() error
+}
+
+// NotifyDialer opens new NotifyConn.
+type NotifyDialer func(ctx context.Context) (NotifyConn, error)
+
+// errConnectionLost is returned by pqConn when its connection is lost,
+// notifications sent before it is reestablished are missed.
+var errConnectionLost = errors.New("notifications connection is lost")
+
+// NewPQDialer creates NotifyDialer opening connections with lib/pq listener.
+func NewPQDialer(dsn string) NotifyDialer {
+	return func(ctx context.Context) (NotifyConn, error) {
+		c := &pqConn{
+			events: make(chan pqEvent, 1),
+		}
+
+		// Listener reconnects by itself, but notifications are lost while it is disconnected,
+		// so connection is closed on the first failure and caller decides when to dial again.
+		c.l = pq.NewListener(dsn, time.Second, time.Second, c.event)
+
+		select {
+		case <-ctx.Done():
+			c.l.Close()
+			return nil, ctx.Err()
+		case e := <-c.events:
+			if e.typ != pq.ListenerEventConnected {
+				c.l.Close()
+				return nil, e.err
+			}
+		}
+
+		return c, nil
+	}
+}
+
+type pqEvent struct {
+	typ pq.ListenerEventType
+	err error
+}
+
+// pqConn implements NotifyConn over pq.Listener.
+type pqConn struct {
+	l      *pq.Listener
+	events chan pqEvent
+}
+
+// event is called by listener's goroutine, only the first event is kept:
+// it is either result of connection attempt or loss of connection.
+func (c *pqConn) event(typ pq.ListenerEventType, err error) {
+	select {
+	case c.events <- pqEvent{typ: typ, err: err}:
+	default:
+	}
+}
+
+func (c *pqConn) Listen(ctx context.Context, channel string) error {
+	return c.l.Listen(channel)
+}
+
+func (c *pqConn) WaitForNotification(ctx context.Context) (string, string, error) {
+	select {
+	case <-ctx.Done():
+		return "", "", ctx.Err()
+	case e := <-c.events:
+		if e.err != nil {
+			return "", "", errors.Wrap(errConnectionLost, e.err.Error())
+		}
+		return "", "", errConnectionLost
+	case n := <-c.l.Notify:
+		if n == nil {
+			// Listener has reconnected or is closed
+			return "", "", errConnectionLost
+		}
+
+		return n.Channel, n.Extra, nil
+	}
+}
+
+func (c *pqConn) Close() error {
+	return c.l.Close()
+}
+
+func NewNotificationListener(dial NotifyDialer) *NotificationListener {
+	return &NotificationListener{
+		dial: dial,
+	}
+}
+
+// NotificationListener is a postgres LISTEN/NOTIFY listener of published notifications
+type NotificationListener struct {
+	dial NotifyDialer
+}
+
+// Listen implements dataprovider.NotificationListener, every call opens new connection.
+func (l *NotificationListener) Listen(
+	ctx context.Context,
+	ready func() error,
+	published func(id int) error,
+) error {
+	conn, err := l.dial(ctx)
+	if err != nil {
+		return errors.Wrap(err, "connecting notifications listener")
+	}
+	defer conn.Close()
+
+	err = conn.Listen(ctx, publishedChannel)
+	if err != nil {
+		return errors.Wrapf(err, "listening to %s", publishedChannel)
+	}
+
+	err = ready()
+	if err != nil {
+		return err
+	}
+
+	for {
+		channel, payload, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return errors.Wrap(err, "waiting for published notifications")
+		}
+
+		if channel != publishedChannel {
+			continue
+		}
+
+		id, err := strconv.Atoi(payload)
+		if err != nil {
+			return errors.Wrapf(err, "parsing payload %q of %s", payload, publishedChannel)
+		}
+
+		err = published(id)
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -2,14 +2,38 @@ package pg
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 
+	"github.com/hummerd/gophercon/internal/dataprovider"
 	"github.com/hummerd/gophercon/internal/model"
 )
 
+var notificationColumns = []string{
+	"n.id",
+	"n.user_id",
+	"n.title",
+	"n.body",
+	"n.type",
+	"n.priority",
+	"n.from_time",
+	"n.till_time",
+	"n.created_at",
+	"n.publish_at",
+	"n.published_at",
+	"n.publish_seq",
+	"n.revoked_at",
+	"n.version",
+	"COALESCE(n.collapse_key, '') AS collapse_key",
+	"n.collapse_count",
+	"n.last_seen_at",
+	"n.language::text AS language",
+}
+
 func NewNotificationStore(db sqlx.ExtContext) *NotificationStore {
 	return &NotificationStore{
 		db: db,
@@ -21,52 +45,953 @@ type NotificationStore struct {
 	db sqlx.ExtContext
 }
 
-// Insert inserts new notification
+// Insert inserts new notification along with its translations,
+// event of published notification is stored to outbox.
+// Notification with collapse key is merged into active notification with the same key and recipient.
 func (s *NotificationStore) Insert(ctx context.Context, notification *model.Notification) error {
+	return withTx(ctx, s.db, func(tx sqlx.ExtContext) error {
+		if notification.CollapseKey != "" {
+			return collapseNotification(ctx, tx, notification)
+		}
+
+		err := insertNotification(ctx, tx, notification)
+		if err != nil {
+			return err
+		}
+
+		err = insertTranslations(ctx, tx, []*model.Notification{notification})
+		if err != nil {
+			return err
+		}
+
+		return insertOutbox(ctx, tx, model.EventNotificationPublished, publishedOnly([]*model.Notification{notification}))
+	})
+}
+
+// publishedOnly filters notifications that are published already
+func publishedOnly(notifications []*model.Notification) []*model.Notification {
+	res := make([]*model.Notification, 0, len(notifications))
+	for _, n := range notifications {
+		if n.PublishedAt != nil {
+			res = append(res, n)
+		}
+	}
+
+	return res
+}
+
+func insertNotification(ctx context.Context, db sqlx.ExtContext, notification *model.Notification) error {
 	query, args, _ := sq.Insert("app.notifications").
+		SetMap(map[string]interface{}{
+			"type":         notification.Type,
+			"priority":     notification.Priority,
+			"title":        notification.Title,
+			"body":         notification.Body,
+			"user_id":      notification.UserID,
+			"from_time":    notification.FromTime,
+			"till_time":    notification.TillTime,
+			"publish_at":   notification.PublishAt,
+			"published_at": notification.PublishedAt,
+			"language":     language(notification),
+		}).
+		Suffix("returning id, created_at, version, collapse_count, last_seen_at, publish_seq;").
+		PlaceholderFormat(sq.Dollar).ToSql()
+
+	r := db.QueryRowxContext(ctx, query, args...)
+
+	err := r.Scan(
+		&notification.ID,
+		&notification.CreatedAt,
+		&notification.Version,
+		&notification.CollapseCount,
+		&notification.LastSeenAt,
+		&notification.PublishSeq,
+	)
+	if err != nil {
+		return errors.Wrap(err, "can't scan notification id")
+	}
+
+	return nil
+}
+
+// language returns text search configuration of notification, database default is used when it is not set
+func language(notification *model.Notification) interface{} {
+	if notification.Language == "" {
+		return sq.Expr("DEFAULT")
+	}
+
+	return notification.Language
+}
+
+// insertBatchSize limits number of rows inserted by single statement,
+// postgres allows 65535 parameters per statement
+const insertBatchSize = 1000
+
+// InsertBatch inserts notifications and their translations in single transaction using multi-row inserts,
+// events of published notifications are stored to outbox.
+// Notifications with collapse keys are merged one by one as they may collapse into each other.
+func (s *NotificationStore) InsertBatch(ctx context.Context, notifications []*model.Notification) error {
+	return withTx(ctx, s.db, func(tx sqlx.ExtContext) error {
+		plain := make([]*model.Notification, 0, len(notifications))
+
+		for _, n := range notifications {
+			if n.CollapseKey == "" {
+				plain = append(plain, n)
+				continue
+			}
+
+			err := collapseNotification(ctx, tx, n)
+			if err != nil {
+				return err
+			}
+		}
+
+		for start := 0; start < len(plain); start += insertBatchSize {
+			end := start + insertBatchSize
+			if end > len(plain) {
+				end = len(plain)
+			}
+
+			err := insertBatch(ctx, tx, plain[start:end])
+			if err != nil {
+				return err
+			}
+		}
+
+		err := insertTranslations(ctx, tx, plain)
+		if err != nil {
+			return err
+		}
+
+		return insertOutbox(ctx, tx, model.EventNotificationPublished, publishedOnly(plain))
+	})
+}
+
+func insertBatch(ctx context.Context, db sqlx.ExtContext, notifications []*model.Notification) error {
+	qb := sq.Insert("app.notifications").
+		Columns("type", "priority", "title", "body", "user_id", "from_time", "till_time", "publish_at", "published_at", "language").
+		Suffix("returning id, created_at, version, collapse_count, last_seen_at, publish_seq").
+		PlaceholderFormat(sq.Dollar)
+
+	for _, n := range notifications {
+		qb = qb.Values(n.Type, n.Priority, n.Title, n.Body, n.UserID, n.FromTime, n.TillTime, n.PublishAt, n.PublishedAt, language(n))
+	}
+
+	query, args, err := qb.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for inserting notifications")
+	}
+
+	rows, err := db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrapf(err, "inserting %d notifications", len(notifications))
+	}
+	defer rows.Close()
+
+	// Rows of single insert statement are returned in the order of VALUES list
+	i := 0
+	for ; rows.Next(); i++ {
+		n := notifications[i]
+		err = rows.Scan(&n.ID, &n.CreatedAt, &n.Version, &n.CollapseCount, &n.LastSeenAt, &n.PublishSeq)
+		if err != nil {
+			return errors.Wrap(err, "can't scan notification id")
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return errors.Wrapf(err, "inserting %d notifications", len(notifications))
+	}
+
+	if i != len(notifications) {
+		return errors.Errorf("inserted %d notifications instead of %d", i, len(notifications))
+	}
+
+	return nil
+}
+
+// collapseNotification inserts notification with collapse key or merges it into active notification
+// with the same key and recipient. Merged notification gets content of the new one, its counter and
+// last seen time are bumped and it becomes unread again. Concurrent inserts are serialized by unique index.
+func collapseNotification(ctx context.Context, db sqlx.ExtContext, notification *model.Notification) error {
+	uid := int64(-1)
+	if notification.UserID != nil {
+		uid = *notification.UserID
+	}
+
+	// Expired notification is not merged into, it releases its key
+	query, args, err := sq.Update("app.notifications").
+		Set("collapse_key", nil).
+		Where(sq.Eq{"collapse_key": notification.CollapseKey, "revoked_at": nil}).
+		Where(sq.Expr("COALESCE(user_id, -1) = ?", uid)).
+		Where(sq.Expr("till_time <= now()")).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for releasing expired collapse key")
+	}
+
+	_, err = db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrapf(err, "releasing expired collapse key %q", notification.CollapseKey)
+	}
+
+	query, args, err = sq.Insert("app.notifications AS n").
+		SetMap(map[string]interface{}{
+			"type":         notification.Type,
+			"priority":     notification.Priority,
+			"title":        notification.Title,
+			"body":         notification.Body,
+			"user_id":      notification.UserID,
+			"from_time":    notification.FromTime,
+			"till_time":    notification.TillTime,
+			"publish_at":   notification.PublishAt,
+			"published_at": notification.PublishedAt,
+			"collapse_key": notification.CollapseKey,
+			"language":     language(notification),
+		}).
+		Suffix(`ON CONFLICT ((COALESCE(user_id, -1)), collapse_key)
+			WHERE collapse_key IS NOT NULL AND revoked_at IS NULL
+		DO UPDATE SET
+			type = excluded.type,
+			priority = excluded.priority,
+			title = excluded.title,
+			body = excluded.body,
+			language = excluded.language,
+			from_time = excluded.from_time,
+			till_time = excluded.till_time,
+			publish_at = CASE WHEN n.published_at IS NULL THEN excluded.publish_at ELSE n.publish_at END,
+			published_at = COALESCE(n.published_at, excluded.published_at),
+			collapse_count = n.collapse_count + 1,
+			last_seen_at = now(),
+			version = n.version + 1
+		returning id, created_at, version, collapse_count, last_seen_at, publish_at, published_at, publish_seq,
+			xmax = 0, published_at IS NOT DISTINCT FROM ?`, notification.PublishedAt).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for collapsing notification")
+	}
+
+	// publishedNow is false when notification is merged into already published one
+	var inserted, publishedNow bool
+
+	err = db.QueryRowxContext(ctx, query, args...).Scan(
+		&notification.ID,
+		&notification.CreatedAt,
+		&notification.Version,
+		&notification.CollapseCount,
+		&notification.LastSeenAt,
+		&notification.PublishAt,
+		&notification.PublishedAt,
+		&notification.PublishSeq,
+		&inserted,
+		&publishedNow,
+	)
+	if err != nil {
+		return errors.Wrapf(err, "collapsing notification by key %q", notification.CollapseKey)
+	}
+
+	if !inserted {
+		for _, table := range []string{"app.notification_translations", "app.notification_reads"} {
+			query, args, err := sq.Delete(table).
+				Where(sq.Eq{"notification_id": notification.ID}).
+				PlaceholderFormat(sq.Dollar).
+				ToSql()
+			if err != nil {
+				return errors.Wrapf(err, "creating sql query for deleting from %s", table)
+			}
+
+			_, err = db.ExecContext(ctx, query, args...)
+			if err != nil {
+				return errors.Wrapf(err, "deleting from %s of notification %d", table, notification.ID)
+			}
+		}
+	}
+
+	err = insertTranslations(ctx, db, []*model.Notification{notification})
+	if err != nil {
+		return err
+	}
+
+	if notification.PublishedAt == nil || !publishedNow {
+		return nil
+	}
+
+	return insertOutbox(ctx, db, model.EventNotificationPublished, []*model.Notification{notification})
+}
+
+// Get gets notification by id, revoked notifications are returned as well
+func (s *NotificationStore) Get(ctx context.Context, id int) (*model.Notification, error) {
+	query, args, err := sq.Select(notificationColumns...).
+		From("app.notifications n").
+		Where(sq.Eq{"n.id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for getting notification by id")
+	}
+
+	notification := new(model.Notification)
+
+	err = sqlx.GetContext(ctx, s.db, notification, query, args...)
+	if err == sql.ErrNoRows {
+		return nil, dataprovider.ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "selecting notification %d", id)
+	}
+
+	err = s.loadTranslations(ctx, []*model.Notification{notification})
+	if err != nil {
+		return nil, err
+	}
+
+	return notification, nil
+}
+
+// Update updates notification content if notification's version was not changed since it was read,
+// on success version is incremented
+func (s *NotificationStore) Update(ctx context.Context, notification *model.Notification) error {
+	query, args, err := sq.Update("app.notifications").
 		SetMap(map[string]interface{}{
 			"type":      notification.Type,
+			"priority":  notification.Priority,
 			"title":     notification.Title,
 			"body":      notification.Body,
-			"user_id":   notification.UserID,
 			"from_time": notification.FromTime,
 			"till_time": notification.TillTime,
+			"version":   sq.Expr("version + 1"),
 		}).
-		Suffix("returning id;").
-		PlaceholderFormat(sq.Dollar).ToSql()
+		Where(sq.Eq{
+			"id":         notification.ID,
+			"version":    notification.Version,
+			"revoked_at": nil,
+		}).
+		Suffix("returning version").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for updating notification")
+	}
+
+	err = s.db.QueryRowxContext(ctx, query, args...).Scan(&notification.Version)
+	if err == sql.ErrNoRows {
+		return s.missingOrConflict(ctx, notification.ID)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "updating notification %d", notification.ID)
+	}
+
+	return nil
+}
 
-	r := s.db.QueryRowxContext(ctx, query, args...)
+// Revoke softly deletes notification, revoked notification is kept for audit purposes
+// but it is not visible to users anymore. Revoking revoked notification has no effect.
+func (s *NotificationStore) Revoke(ctx context.Context, id int) error {
+	query, args, err := sq.Update("app.notifications").
+		Set("revoked_at", sq.Expr("now()")).
+		Set("version", sq.Expr("version + 1")).
+		Where(sq.Eq{"id": id}).
+		Where(sq.Eq{"revoked_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for revoking notification")
+	}
 
-	err := r.Scan(&notification.ID)
+	res, err := s.db.ExecContext(ctx, query, args...)
 	if err != nil {
-		return errors.Wrap(err, "can't scan notification id")
+		return errors.Wrapf(err, "revoking notification %d", id)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrapf(err, "revoking notification %d", id)
+	}
+
+	if n == 0 {
+		_, err = s.Get(ctx, id)
+		return err
 	}
 
 	return nil
 }
 
-// GetByUser gets all global notifications or associated with user
-func (s *NotificationStore) GetByUser(ctx context.Context, user *model.User) ([]*model.Notification, error) {
-	notifications := make([]*model.Notification, 0)
+// PublishDue claims up to limit scheduled notifications which publishing time has come,
+// marks them published and stores their events to outbox. Claimed rows are locked with SKIP LOCKED
+// so several dispatchers may run concurrently. Number of published notifications is returned.
+func (s *NotificationStore) PublishDue(ctx context.Context, limit int) (int, error) {
+	var published int
 
-	query, args, err := sq.Select("*").
-		Where(
-			sq.Or{
-				sq.Eq{"user_id": user.ID},
-				sq.Eq{"user_id": nil},
-			},
+	err := withTx(ctx, s.db, func(tx sqlx.ExtContext) error {
+		query, args, err := sq.Select(notificationColumns...).
+			From("app.notifications n").
+			Where(sq.Eq{"n.published_at": nil, "n.revoked_at": nil}).
+			Where(sq.LtOrEq{"n.publish_at": time.Now()}).
+			OrderBy("n.publish_at", "n.id").
+			Limit(uint64(limit)).
+			Suffix("FOR UPDATE SKIP LOCKED").
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			return errors.Wrap(err, "creating sql query for claiming due notifications")
+		}
+
+		notifications := make([]*model.Notification, 0, limit)
+
+		err = sqlx.SelectContext(ctx, tx, &notifications, query, args...)
+		if err != nil {
+			return errors.Wrapf(err, "selecting due notifications with query %s", query)
+		}
+
+		if len(notifications) == 0 {
+			return nil
+		}
+
+		ids := make([]int, len(notifications))
+		for i, n := range notifications {
+			ids[i] = n.ID
+		}
+
+		query, args, err = sq.Update("app.notifications").
+			Set("published_at", sq.Expr("now()")).
+			Where(sq.Eq{"id": ids}).
+			Suffix("returning id, published_at, publish_seq").
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			return errors.Wrap(err, "creating sql query for marking notifications published")
+		}
+
+		rows, err := tx.QueryxContext(ctx, query, args...)
+		if err != nil {
+			return errors.Wrap(err, "marking notifications published")
+		}
+		defer rows.Close()
+
+		byID := make(map[int]*model.Notification, len(notifications))
+		for _, n := range notifications {
+			byID[n.ID] = n
+		}
+
+		for rows.Next() {
+			var (
+				id  int
+				at  time.Time
+				seq int64
+			)
+			if err := rows.Scan(&id, &at, &seq); err != nil {
+				return errors.Wrap(err, "can't scan published notification")
+			}
+			byID[id].PublishedAt = &at
+			byID[id].PublishSeq = &seq
+		}
+
+		if err := rows.Err(); err != nil {
+			return errors.Wrap(err, "marking notifications published")
+		}
+
+		if err := insertOutbox(ctx, tx, model.EventNotificationPublished, notifications); err != nil {
+			return err
+		}
+
+		published = len(notifications)
+		return nil
+	})
+
+	return published, err
+}
+
+// missingOrConflict figures out why notification was not updated
+func (s *NotificationStore) missingOrConflict(ctx context.Context, id int) error {
+	n, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if n.RevokedAt != nil {
+		return dataprovider.ErrNotFound
+	}
+
+	return dataprovider.ErrVersionConflict
+}
+
+// GetByUser gets page of active global notifications or associated with user
+// along with user's read status, higher priority and then newest notifications go first
+func (s *NotificationStore) GetByUser(ctx context.Context, user *model.User, filter *model.InboxFilter) ([]*model.Notification, error) {
+	notifications := make([]*model.Notification, 0, filter.Limit)
+
+	qb := sq.Select(notificationColumns...).
+		Columns("r.read_at", "r.read_at IS NOT NULL AS read").
+		From("app.notifications n").
+		LeftJoin("app.notification_reads r ON r.notification_id = n.id AND r.user_id = ?", user.ID).
+		Where(visibleTo(user)).
+		Where(activeAt(time.Now())).
+		OrderBy("n.priority DESC", "n.created_at DESC", "n.id DESC").
+		PlaceholderFormat(sq.Dollar)
+
+	if len(filter.Types) > 0 {
+		qb = qb.Where(sq.Eq{"n.type": filter.Types})
+	}
+
+	if len(filter.Priorities) > 0 {
+		qb = qb.Where(sq.Eq{"n.priority": filter.Priorities})
+	}
+
+	if filter.CreatedFrom != nil {
+		qb = qb.Where(sq.GtOrEq{"n.created_at": *filter.CreatedFrom})
+	}
+
+	if filter.CreatedTill != nil {
+		qb = qb.Where(sq.Lt{"n.created_at": *filter.CreatedTill})
+	}
+
+	if filter.Read != nil {
+		if *filter.Read {
+			qb = qb.Where(sq.NotEq{"r.notification_id": nil})
+		} else {
+			qb = qb.Where(sq.Eq{"r.notification_id": nil})
+		}
+	}
+
+	if filter.After != nil {
+		qb = qb.Where("(n.priority, n.created_at, n.id) < (?, ?, ?)",
+			filter.After.Priority, filter.After.CreatedAt, filter.After.ID)
+	}
+
+	if filter.Limit > 0 {
+		qb = qb.Limit(uint64(filter.Limit))
+	}
+
+	query, args, err := qb.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for getting notifications by user id")
+	}
+
+	err = sqlx.SelectContext(ctx, s.db, &notifications, query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "selecting notifications from database with query %s", query)
+	}
+
+	err = s.loadTranslations(ctx, notifications)
+	if err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+// Search gets page of notifications visible to user matching full-text query along with user's read status,
+// the most relevant and then newest notifications go first. Expired notifications are matched as well.
+func (s *NotificationStore) Search(ctx context.Context, user *model.User, filter *model.SearchFilter) ([]*model.SearchResult, error) {
+	results := make([]*model.SearchResult, 0, filter.Limit)
+
+	rank := "ts_rank_cd(n.search_vector, q)::float8"
+
+	// Headlines are expensive, postgres computes them only for the rows of the page.
+	// Content is escaped before highlighting so the only markup of headline is <mark> tags.
+	qb := sq.Select(notificationColumns...).
+		Columns(
+			"r.read_at",
+			"r.read_at IS NOT NULL AS read",
+			rank+" AS rank",
+			`ts_headline(n.language, `+escapeHTML("n.title")+`, q, 'HighlightAll=true, StartSel=<mark>, StopSel=</mark>') AS "headline.title"`,
+			`ts_headline(n.language, `+escapeHTML("n.body")+`, q, `+
+				`'MaxFragments=2, MinWords=10, MaxWords=30, StartSel=<mark>, StopSel=</mark>') AS "headline.body"`,
 		).
-		From("app.notifications").
+		From("app.notifications n").
+		JoinClause("CROSS JOIN websearch_to_tsquery(?::regconfig, ?) q", filter.Language, filter.Query).
+		LeftJoin("app.notification_reads r ON r.notification_id = n.id AND r.user_id = ?", user.ID).
+		Where("n.search_vector @@ q").
+		Where(visibleTo(user)).
+		Where(sq.Or{
+			sq.Eq{"n.from_time": nil},
+			sq.LtOrEq{"n.from_time": time.Now()},
+		}).
+		OrderBy("rank DESC", "n.id DESC").
+		PlaceholderFormat(sq.Dollar)
+
+	if filter.After != nil {
+		qb = qb.Where("("+rank+", n.id) < (?, ?)", filter.After.Rank, filter.After.ID)
+	}
+
+	if filter.Limit > 0 {
+		qb = qb.Limit(uint64(filter.Limit))
+	}
+
+	query, args, err := qb.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for searching notifications")
+	}
+
+	err = sqlx.SelectContext(ctx, s.db, &results, query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "searching notifications with query %s", query)
+	}
+
+	return results, nil
+}
+
+// GetPublishedAfter gets up to limit active notifications visible to user
+// that were published after position, notifications are ordered by publishing sequence number
+func (s *NotificationStore) GetPublishedAfter(
+	ctx context.Context,
+	user *model.User,
+	pos *model.StreamPosition,
+	limit int,
+) ([]*model.Notification, error) {
+	return s.publishedAfter(ctx, sq.And{visibleTo(user), activeAt(time.Now())}, pos, limit)
+}
+
+// FindPublishedAfter gets up to limit not revoked notifications of all users
+// that were published after position, notifications are ordered by publishing sequence number
+func (s *NotificationStore) FindPublishedAfter(
+	ctx context.Context,
+	pos *model.StreamPosition,
+	limit int,
+) ([]*model.Notification, error) {
+	return s.publishedAfter(ctx, sq.Eq{"n.revoked_at": nil}, pos, limit)
+}
+
+// LastStreamPosition gets position of the latest published notification,
+// position with zero sequence number is returned if nothing is published yet.
+func (s *NotificationStore) LastStreamPosition(ctx context.Context) (*model.StreamPosition, error) {
+	pos := &model.StreamPosition{}
+
+	err := sqlx.GetContext(ctx, s.db, &pos.PublishSeq, "SELECT COALESCE(max(publish_seq), 0) FROM app.notifications")
+	if err != nil {
+		return nil, errors.Wrap(err, "getting last published notification")
+	}
+
+	return pos, nil
+}
+
+func (s *NotificationStore) publishedAfter(
+	ctx context.Context,
+	where sq.Sqlizer,
+	pos *model.StreamPosition,
+	limit int,
+) ([]*model.Notification, error) {
+	notifications := make([]*model.Notification, 0, limit)
+
+	query, args, err := sq.Select(notificationColumns...).
+		From("app.notifications n").
+		Where(where).
+		Where(sq.Gt{"n.publish_seq": pos.PublishSeq}).
+		OrderBy("n.publish_seq").
+		Limit(uint64(limit)).
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
 	if err != nil {
-		return nil, errors.Wrap(err, "creating sql query for getting user ids by user id")
+		return nil, errors.Wrap(err, "creating sql query for getting published notifications")
 	}
 
 	err = sqlx.SelectContext(ctx, s.db, &notifications, query, args...)
 	if err != nil {
-		return nil, errors.Wrapf(err, "selecting user ids from database with query %s", query)
+		return nil, errors.Wrapf(err, "selecting notifications from database with query %s", query)
+	}
+
+	err = s.loadTranslations(ctx, notifications)
+	if err != nil {
+		return nil, err
 	}
 
 	return notifications, nil
 }
+
+// MarkRead marks notification visible to user as read, marking is idempotent.
+// dataprovider.ErrNotFound is returned if notification does not exist or is not visible to user.
+func (s *NotificationStore) MarkRead(ctx context.Context, user *model.User, id int) error {
+	query, args, err := sq.Insert("app.notification_reads").
+		Columns("user_id", "notification_id").
+		Select(
+			sq.Select().
+				Column("?::bigint", user.ID).
+				Column("n.id").
+				From("app.notifications n").
+				Where(sq.Eq{"n.id": id}).
+				Where(visibleTo(user)),
+		).
+		Suffix("ON CONFLICT DO NOTHING").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for marking notification as read")
+	}
+
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrapf(err, "marking notification %d as read for user %d", id, user.ID)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrapf(err, "marking notification %d as read for user %d", id, user.ID)
+	}
+
+	// Nothing is inserted for notification which is already read as well
+	if n == 0 {
+		return s.checkVisible(ctx, user, id)
+	}
+
+	return nil
+}
+
+// checkVisible returns dataprovider.ErrNotFound if notification is not visible to user
+func (s *NotificationStore) checkVisible(ctx context.Context, user *model.User, id int) error {
+	query, args, err := sq.Select("count(*)").
+		From("app.notifications n").
+		Where(sq.Eq{"n.id": id}).
+		Where(visibleTo(user)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for checking notification visibility")
+	}
+
+	var count int
+	err = sqlx.GetContext(ctx, s.db, &count, query, args...)
+	if err != nil {
+		return errors.Wrapf(err, "checking visibility of notification %d for user %d", id, user.ID)
+	}
+
+	if count == 0 {
+		return dataprovider.ErrNotFound
+	}
+
+	return nil
+}
+
+// MarkAllRead marks all active notifications visible to user as read
+func (s *NotificationStore) MarkAllRead(ctx context.Context, user *model.User) error {
+	query, args, err := sq.Insert("app.notification_reads").
+		Columns("user_id", "notification_id").
+		Select(
+			sq.Select().
+				Column("?::bigint", user.ID).
+				Column("n.id").
+				From("app.notifications n").
+				Where(visibleTo(user)).
+				Where(activeAt(time.Now())),
+		).
+		Suffix("ON CONFLICT DO NOTHING").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for marking all notifications as read")
+	}
+
+	_, err = s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrapf(err, "marking all notifications as read for user %d", user.ID)
+	}
+
+	return nil
+}
+
+// CountUnread counts active notifications visible to user which are not read yet
+func (s *NotificationStore) CountUnread(ctx context.Context, user *model.User) (int, error) {
+	query, args, err := sq.Select("count(*)").
+		From("app.notifications n").
+		LeftJoin("app.notification_reads r ON r.notification_id = n.id AND r.user_id = ?", user.ID).
+		Where(visibleTo(user)).
+		Where(activeAt(time.Now())).
+		Where(sq.Eq{"r.notification_id": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return 0, errors.Wrap(err, "creating sql query for counting unread notifications")
+	}
+
+	var count int
+	err = sqlx.GetContext(ctx, s.db, &count, query, args...)
+	if err != nil {
+		return 0, errors.Wrapf(err, "counting unread notifications with query %s", query)
+	}
+
+	return count, nil
+}
+
+// Find gets page of notifications matching filter regardless of recipient, newest notifications go first
+func (s *NotificationStore) Find(ctx context.Context, filter *model.NotificationFilter) ([]*model.Notification, error) {
+	notifications := make([]*model.Notification, 0, filter.Limit)
+
+	qb := sq.Select(notificationColumns...).
+		From("app.notifications n").
+		OrderBy("n.created_at DESC", "n.id DESC").
+		PlaceholderFormat(sq.Dollar)
+
+	if filter.UserID != nil {
+		qb = qb.Where(sq.Eq{"n.user_id": *filter.UserID})
+	}
+
+	now := time.Now()
+
+	switch filter.State {
+	case model.NotificationStateActive:
+		qb = qb.Where(sq.Eq{"n.revoked_at": nil}).Where(activeAt(now))
+	case model.NotificationStateExpired:
+		qb = qb.Where(sq.Eq{"n.revoked_at": nil}).Where(sq.LtOrEq{"n.till_time": now})
+	case model.NotificationStatePending:
+		qb = qb.Where(sq.Eq{"n.revoked_at": nil}).Where(sq.Gt{"n.from_time": now})
+	case model.NotificationStateRevoked:
+		qb = qb.Where(sq.NotEq{"n.revoked_at": nil})
+	case model.NotificationStateScheduled:
+		qb = qb.Where(sq.Eq{"n.revoked_at": nil, "n.published_at": nil})
+	}
+
+	if filter.After != nil {
+		qb = qb.Where("(n.created_at, n.id) < (?, ?)", filter.After.CreatedAt, filter.After.ID)
+	}
+
+	if filter.Limit > 0 {
+		qb = qb.Limit(uint64(filter.Limit))
+	}
+
+	query, args, err := qb.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for finding notifications")
+	}
+
+	err = sqlx.SelectContext(ctx, s.db, &notifications, query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "selecting notifications from database with query %s", query)
+	}
+
+	err = s.loadTranslations(ctx, notifications)
+	if err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+func insertTranslations(ctx context.Context, db sqlx.ExtContext, notifications []*model.Notification) error {
+	newInsert := func() sq.InsertBuilder {
+		return sq.Insert("app.notification_translations").
+			Columns("notification_id", "locale", "title", "body").
+			PlaceholderFormat(sq.Dollar)
+	}
+
+	qb := newInsert()
+	rows := 0
+
+	for _, n := range notifications {
+		for locale, c := range n.Translations {
+			qb = qb.Values(n.ID, locale, c.Title, c.Body)
+			rows++
+
+			if rows == insertBatchSize {
+				if err := execInsert(ctx, db, qb); err != nil {
+					return err
+				}
+
+				qb = newInsert()
+				rows = 0
+			}
+		}
+	}
+
+	if rows == 0 {
+		return nil
+	}
+
+	return execInsert(ctx, db, qb)
+}
+
+func execInsert(ctx context.Context, db sqlx.ExtContext, qb sq.InsertBuilder) error {
+	query, args, err := qb.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql insert query")
+	}
+
+	_, err = db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrapf(err, "executing insert query %s", query)
+	}
+
+	return nil
+}
+
+type translation struct {
+	NotificationID int    `db:"notification_id"`
+	Locale         string `db:"locale"`
+	model.NotificationContent
+}
+
+// loadTranslations fills translations of notifications
+func (s *NotificationStore) loadTranslations(ctx context.Context, notifications []*model.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*model.Notification, len(notifications))
+	ids := make([]int, 0, len(notifications))
+	for _, n := range notifications {
+		byID[n.ID] = n
+		ids = append(ids, n.ID)
+	}
+
+	query, args, err := sq.Select("notification_id", "locale", "title", "body").
+		From("app.notification_translations").
+		Where(sq.Eq{"notification_id": ids}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for getting translations")
+	}
+
+	translations := make([]translation, 0)
+
+	err = sqlx.SelectContext(ctx, s.db, &translations, query, args...)
+	if err != nil {
+		return errors.Wrapf(err, "selecting translations from database with query %s", query)
+	}
+
+	for _, t := range translations {
+		n := byID[t.NotificationID]
+		if n.Translations == nil {
+			n.Translations = make(map[string]model.NotificationContent)
+		}
+		n.Translations[t.Locale] = t.NotificationContent
+	}
+
+	return nil
+}
+
+// visibleTo matches published and not revoked notifications addressed to user and global ones,
+// notifications of types muted by user are excluded unless they are critical
+func visibleTo(user *model.User) sq.Sqlizer {
+	return sq.And{
+		sq.Or{
+			sq.Eq{"n.user_id": user.ID},
+			sq.Eq{"n.user_id": nil},
+		},
+		sq.Eq{"n.revoked_at": nil},
+		sq.NotEq{"n.published_at": nil},
+		sq.Or{
+			sq.NotEq{"n.type": user.MutedTypes},
+			sq.Eq{"n.priority": model.PriorityCritical},
+		},
+	}
+}
+
+// activeAt matches notifications which visibility window contains t
+func activeAt(t time.Time) sq.Sqlizer {
+	return sq.And{
+		sq.Or{
+			sq.Eq{"n.from_time": nil},
+			sq.LtOrEq{"n.from_time": t},
+		},
+		sq.Or{
+			sq.Eq{"n.till_time": nil},
+			sq.Gt{"n.till_time": t},
+		},
+	}
+}
+
+// escapeHTML returns sql expression escaping HTML special characters of text column.
+func escapeHTML(column string) string {
+	return "replace(replace(replace(replace(replace(" + column +
+		`, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;')`
+}
@@ -0,0 +1,277 @@
+package pg
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/dataprovider"
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+var outboxColumns = []string{
+	"id",
+	"event",
+	"notification_id",
+	"status",
+	"attempts",
+	"error",
+	"next_attempt_at",
+	"created_at",
+	"published_at",
+}
+
+func NewOutboxStore(db sqlx.ExtContext) *OutboxStore {
+	return &OutboxStore{
+		db: db,
+	}
+}
+
+// OutboxStore is an outbox postgres store
+type OutboxStore struct {
+	db sqlx.ExtContext
+}
+
+// insertOutbox stores pending event of every notification, it is called within
+// transaction that changes notifications.
+func insertOutbox(ctx context.Context, db sqlx.ExtContext, event string, notifications []*model.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	for start := 0; start < len(notifications); start += insertBatchSize {
+		end := start + insertBatchSize
+		if end > len(notifications) {
+			end = len(notifications)
+		}
+
+		insert := sq.Insert("app.outbox").
+			Columns("event", "notification_id")
+
+		for _, n := range notifications[start:end] {
+			insert = insert.Values(event, n.ID)
+		}
+
+		query, args, err := insert.PlaceholderFormat(sq.Dollar).ToSql()
+		if err != nil {
+			return errors.Wrap(err, "creating sql query for inserting outbox entries")
+		}
+
+		_, err = db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return errors.Wrapf(err, "inserting %d outbox entries", end-start)
+		}
+	}
+
+	return nil
+}
+
+// Get gets outbox entry by id
+func (s *OutboxStore) Get(ctx context.Context, id int64) (*model.OutboxEntry, error) {
+	query, args, err := sq.Select(outboxColumns...).
+		From("app.outbox").
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for getting outbox entry")
+	}
+
+	entries := make([]*model.OutboxEntry, 0, 1)
+
+	err = sqlx.SelectContext(ctx, s.db, &entries, query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "selecting outbox entry with query %s", query)
+	}
+
+	if len(entries) == 0 {
+		return nil, dataprovider.ErrNotFound
+	}
+
+	return entries[0], nil
+}
+
+// List gets outbox entries matching filter from the latest
+func (s *OutboxStore) List(ctx context.Context, filter *model.OutboxFilter) ([]*model.OutboxEntry, error) {
+	qb := sq.Select(outboxColumns...).
+		From("app.outbox").
+		OrderBy("id DESC").
+		Limit(uint64(filter.Limit)).
+		PlaceholderFormat(sq.Dollar)
+
+	if filter.Status != "" {
+		qb = qb.Where(sq.Eq{"status": filter.Status})
+	}
+
+	if filter.BeforeID > 0 {
+		qb = qb.Where(sq.Lt{"id": filter.BeforeID})
+	}
+
+	query, args, err := qb.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for listing outbox entries")
+	}
+
+	entries := make([]*model.OutboxEntry, 0, filter.Limit)
+
+	err = sqlx.SelectContext(ctx, s.db, &entries, query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "selecting outbox entries with query %s", query)
+	}
+
+	return entries, nil
+}
+
+// Stats counts pending and failed entries
+func (s *OutboxStore) Stats(ctx context.Context) (*model.OutboxStats, error) {
+	query, args, err := sq.Select(
+		"count(*) FILTER (WHERE status = 'pending') AS pending",
+		"count(*) FILTER (WHERE status = 'failed') AS failed",
+		"min(created_at) FILTER (WHERE status = 'pending') AS oldest_pending_at",
+	).
+		From("app.outbox").
+		Where(sq.NotEq{"status": model.DeliverySucceeded}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for outbox stats")
+	}
+
+	stats := new(model.OutboxStats)
+
+	err = sqlx.GetContext(ctx, s.db, stats, query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "selecting outbox stats with query %s", query)
+	}
+
+	return stats, nil
+}
+
+// ClaimDue claims due pending entries by moving their next attempt time to the end of lease.
+// Rows are locked with SKIP LOCKED so several relays may publish concurrently.
+func (s *OutboxStore) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*model.OutboxEntry, error) {
+	entries := make([]*model.OutboxEntry, 0, limit)
+
+	now := time.Now()
+
+	due, dueArgs, err := sq.Select("id").
+		From("app.outbox").
+		Where(sq.Eq{"status": model.DeliveryPending}).
+		Where(sq.LtOrEq{"next_attempt_at": now}).
+		OrderBy("next_attempt_at", "id").
+		Limit(uint64(limit)).
+		Suffix("FOR UPDATE SKIP LOCKED").
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for selecting due outbox entries")
+	}
+
+	query, args, err := sq.Update("app.outbox").
+		Set("next_attempt_at", now.Add(lease)).
+		Where(sq.Expr("id IN ("+due+")", dueArgs...)).
+		Suffix("returning " + strings.Join(outboxColumns, ", ")).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for claiming due outbox entries")
+	}
+
+	err = sqlx.SelectContext(ctx, s.db, &entries, query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "claiming due outbox entries with query %s", query)
+	}
+
+	// Rows returned by update are not ordered
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+
+	return entries, nil
+}
+
+// Update updates entry's status, attempts, error, next attempt and publishing time
+func (s *OutboxStore) Update(ctx context.Context, entry *model.OutboxEntry) error {
+	query, args, err := sq.Update("app.outbox").
+		SetMap(map[string]interface{}{
+			"status":          entry.Status,
+			"attempts":        entry.Attempts,
+			"error":           entry.Error,
+			"next_attempt_at": entry.NextAttemptAt,
+			"published_at":    entry.PublishedAt,
+		}).
+		Where(sq.Eq{"id": entry.ID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for updating outbox entry")
+	}
+
+	return s.execOne(ctx, query, args, entry.ID)
+}
+
+// Replay makes entry pending again
+func (s *OutboxStore) Replay(ctx context.Context, id int64) error {
+	query, args, err := s.replay().
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for replaying outbox entry")
+	}
+
+	return s.execOne(ctx, query, args, id)
+}
+
+// ReplayFailed makes failed entries pending again
+func (s *OutboxStore) ReplayFailed(ctx context.Context) (int, error) {
+	query, args, err := s.replay().
+		Where(sq.Eq{"status": model.DeliveryFailed}).
+		ToSql()
+	if err != nil {
+		return 0, errors.Wrap(err, "creating sql query for replaying failed outbox entries")
+	}
+
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, errors.Wrap(err, "replaying failed outbox entries")
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrap(err, "replaying failed outbox entries")
+	}
+
+	return int(n), nil
+}
+
+func (s *OutboxStore) replay() sq.UpdateBuilder {
+	return sq.Update("app.outbox").
+		SetMap(map[string]interface{}{
+			"status":          model.DeliveryPending,
+			"attempts":        0,
+			"error":           "",
+			"next_attempt_at": sq.Expr("now()"),
+			"published_at":    nil,
+		}).
+		PlaceholderFormat(sq.Dollar)
+}
+
+func (s *OutboxStore) execOne(ctx context.Context, query string, args []interface{}, id int64) error {
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrapf(err, "updating outbox entry %d", id)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrapf(err, "updating outbox entry %d", id)
+	}
+
+	if n == 0 {
+		return dataprovider.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package pg
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+func NewPreferenceStore(db sqlx.ExtContext) *PreferenceStore {
+	return &PreferenceStore{
+		db: db,
+	}
+}
+
+// PreferenceStore is a notification preferences postgres store
+type PreferenceStore struct {
+	db sqlx.ExtContext
+}
+
+// ListByUser gets all user's preferences
+func (s *PreferenceStore) ListByUser(ctx context.Context, userID int64) ([]*model.Preference, error) {
+	preferences := make([]*model.Preference, 0)
+
+	query, args, err := sq.Select("type", "channel", "enabled").
+		From("app.notification_preferences").
+		Where(sq.Eq{"user_id": userID}).
+		OrderBy("type", "channel").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for listing preferences")
+	}
+
+	err = sqlx.SelectContext(ctx, s.db, &preferences, query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "selecting preferences from database with query %s", query)
+	}
+
+	return preferences, nil
+}
+
+// Replace deletes user's preferences and inserts new ones in single transaction
+func (s *PreferenceStore) Replace(ctx context.Context, userID int64, preferences []*model.Preference) error {
+	return withTx(ctx, s.db, func(tx sqlx.ExtContext) error {
+		query, args, err := sq.Delete("app.notification_preferences").
+			Where(sq.Eq{"user_id": userID}).
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			return errors.Wrap(err, "creating sql query for deleting preferences")
+		}
+
+		_, err = tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return errors.Wrapf(err, "deleting preferences of user %d", userID)
+		}
+
+		if len(preferences) == 0 {
+			return nil
+		}
+
+		insert := sq.Insert("app.notification_preferences").
+			Columns("user_id", "type", "channel", "enabled").
+			PlaceholderFormat(sq.Dollar)
+
+		for _, p := range preferences {
+			insert = insert.Values(userID, p.Type, p.Channel, p.Enabled)
+		}
+
+		query, args, err = insert.ToSql()
+		if err != nil {
+			return errors.Wrap(err, "creating sql query for inserting preferences")
+		}
+
+		_, err = tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return errors.Wrapf(err, "inserting preferences of user %d", userID)
+		}
+
+		return nil
+	})
+}
+
+// GetQuietHours gets user's quiet hours
+func (s *PreferenceStore) GetQuietHours(ctx context.Context, userID int64) (*model.QuietHours, error) {
+	query, args, err := sq.Select("timezone", "start_time", "end_time").
+		From("app.quiet_hours").
+		Where(sq.Eq{"user_id": userID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for getting quiet hours")
+	}
+
+	quietHours := make([]*model.QuietHours, 0, 1)
+
+	err = sqlx.SelectContext(ctx, s.db, &quietHours, query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "selecting quiet hours with query %s", query)
+	}
+
+	if len(quietHours) == 0 {
+		return nil, nil
+	}
+
+	return quietHours[0], nil
+}
+
+// SetQuietHours inserts or replaces user's quiet hours
+func (s *PreferenceStore) SetQuietHours(ctx context.Context, userID int64, quietHours *model.QuietHours) error {
+	query, args, err := sq.Insert("app.quiet_hours").
+		SetMap(map[string]interface{}{
+			"user_id":    userID,
+			"timezone":   quietHours.Timezone,
+			"start_time": quietHours.Start,
+			"end_time":   quietHours.End,
+		}).
+		Suffix("ON CONFLICT (user_id) DO UPDATE SET " +
+			"timezone = excluded.timezone, start_time = excluded.start_time, " +
+			"end_time = excluded.end_time, updated_at = now()").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for setting quiet hours")
+	}
+
+	_, err = s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrapf(err, "setting quiet hours of user %d", userID)
+	}
+
+	return nil
+}
+
+// DeleteQuietHours deletes user's quiet hours, deleting missing quiet hours is not an error
+func (s *PreferenceStore) DeleteQuietHours(ctx context.Context, userID int64) error {
+	query, args, err := sq.Delete("app.quiet_hours").
+		Where(sq.Eq{"user_id": userID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for deleting quiet hours")
+	}
+
+	_, err = s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrapf(err, "deleting quiet hours of user %d", userID)
+	}
+
+	return nil
+}
@@ -0,0 +1,329 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/dataprovider"
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+var scheduleColumns = []string{
+	"id",
+	"cron",
+	"timezone",
+	"catch_up",
+	"user_id",
+	"type",
+	"title",
+	"body",
+	"priority",
+	"paused",
+	"last_error",
+	"next_run_at",
+	"last_run_at",
+	"created_at",
+}
+
+func NewScheduleStore(db sqlx.ExtContext) *ScheduleStore {
+	return &ScheduleStore{
+		db: db,
+	}
+}
+
+// ScheduleStore is a recurring notification schedules postgres store
+type ScheduleStore struct {
+	db sqlx.ExtContext
+}
+
+// Insert inserts new schedule
+func (s *ScheduleStore) Insert(ctx context.Context, schedule *model.Schedule) error {
+	query, args, err := sq.Insert("app.notification_schedules").
+		SetMap(map[string]interface{}{
+			"cron":        schedule.Cron,
+			"timezone":    schedule.Timezone,
+			"catch_up":    schedule.CatchUp,
+			"user_id":     schedule.UserID,
+			"type":        schedule.Type,
+			"title":       schedule.Title,
+			"body":        schedule.Body,
+			"priority":    schedule.Priority,
+			"paused":      schedule.Paused,
+			"next_run_at": schedule.NextRunAt,
+		}).
+		Suffix("returning id, created_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for inserting schedule")
+	}
+
+	err = s.db.QueryRowxContext(ctx, query, args...).Scan(&schedule.ID, &schedule.CreatedAt)
+	if err != nil {
+		return errors.Wrap(err, "can't scan schedule id")
+	}
+
+	return nil
+}
+
+// Get gets schedule by id
+func (s *ScheduleStore) Get(ctx context.Context, id int) (*model.Schedule, error) {
+	query, args, err := sq.Select(scheduleColumns...).
+		From("app.notification_schedules").
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for getting schedule")
+	}
+
+	schedule := new(model.Schedule)
+
+	err = sqlx.GetContext(ctx, s.db, schedule, query, args...)
+	if err == sql.ErrNoRows {
+		return nil, dataprovider.ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "selecting schedule %d", id)
+	}
+
+	return schedule, nil
+}
+
+// List gets all schedules
+func (s *ScheduleStore) List(ctx context.Context) ([]*model.Schedule, error) {
+	schedules := make([]*model.Schedule, 0)
+
+	query, args, err := sq.Select(scheduleColumns...).
+		From("app.notification_schedules").
+		OrderBy("id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for listing schedules")
+	}
+
+	err = sqlx.SelectContext(ctx, s.db, &schedules, query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "selecting schedules from database with query %s", query)
+	}
+
+	return schedules, nil
+}
+
+// Update replaces schedule definition and next run time
+func (s *ScheduleStore) Update(ctx context.Context, schedule *model.Schedule) error {
+	query, args, err := sq.Update("app.notification_schedules").
+		SetMap(map[string]interface{}{
+			"cron":        schedule.Cron,
+			"timezone":    schedule.Timezone,
+			"catch_up":    schedule.CatchUp,
+			"user_id":     schedule.UserID,
+			"type":        schedule.Type,
+			"title":       schedule.Title,
+			"body":        schedule.Body,
+			"priority":    schedule.Priority,
+			"next_run_at": schedule.NextRunAt,
+		}).
+		Where(sq.Eq{"id": schedule.ID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for updating schedule")
+	}
+
+	return s.exec(ctx, schedule.ID, query, args)
+}
+
+// SetPaused pauses or resumes schedule, next run time is replaced as well.
+// Error of failed run is cleared on resume.
+func (s *ScheduleStore) SetPaused(ctx context.Context, id int, paused bool, nextRunAt *time.Time) error {
+	qb := sq.Update("app.notification_schedules").
+		Set("paused", paused).
+		Set("next_run_at", nextRunAt).
+		Where(sq.Eq{"id": id})
+
+	if !paused {
+		qb = qb.Set("last_error", "")
+	}
+
+	query, args, err := qb.
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for pausing schedule")
+	}
+
+	return s.exec(ctx, id, query, args)
+}
+
+// Delete deletes schedule, notifications produced by schedule are kept
+func (s *ScheduleStore) Delete(ctx context.Context, id int) error {
+	query, args, err := sq.Delete("app.notification_schedules").
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for deleting schedule")
+	}
+
+	return s.exec(ctx, id, query, args)
+}
+
+// RunDue claims up to limit active schedules which next run time has come,
+// inserts notifications planned for them and moves them to the next run.
+// Claimed rows are locked with SKIP LOCKED so several instances may run schedules concurrently.
+// Schedule which run fails is paused with the error, other schedules of the batch are not affected.
+// Number of processed schedules is returned.
+func (s *ScheduleStore) RunDue(ctx context.Context, limit int, plan dataprovider.PlanFunc) (int, error) {
+	var processed int
+
+	err := withTx(ctx, s.db, func(tx sqlx.ExtContext) error {
+		query, args, err := sq.Select(scheduleColumns...).
+			From("app.notification_schedules").
+			Where(sq.Eq{"paused": false}).
+			Where(sq.LtOrEq{"next_run_at": time.Now()}).
+			OrderBy("next_run_at", "id").
+			Limit(uint64(limit)).
+			Suffix("FOR UPDATE SKIP LOCKED").
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			return errors.Wrap(err, "creating sql query for claiming due schedules")
+		}
+
+		schedules := make([]*model.Schedule, 0, limit)
+
+		err = sqlx.SelectContext(ctx, tx, &schedules, query, args...)
+		if err != nil {
+			return errors.Wrapf(err, "selecting due schedules with query %s", query)
+		}
+
+		now := time.Now()
+
+		for _, schedule := range schedules {
+			err = runSchedule(ctx, tx, schedule, plan, now)
+			if err != nil {
+				return err
+			}
+		}
+
+		processed = len(schedules)
+		return nil
+	})
+
+	return processed, err
+}
+
+// runSchedule runs schedule within savepoint, so its failure is rolled back
+// and recorded without aborting transaction.
+func runSchedule(
+	ctx context.Context,
+	tx sqlx.ExtContext,
+	schedule *model.Schedule,
+	plan dataprovider.PlanFunc,
+	now time.Time,
+) error {
+	_, err := tx.ExecContext(ctx, "SAVEPOINT run_schedule")
+	if err != nil {
+		return errors.Wrapf(err, "creating savepoint for schedule %d", schedule.ID)
+	}
+
+	runErr := moveSchedule(ctx, tx, schedule, plan, now)
+	if runErr == nil {
+		_, err = tx.ExecContext(ctx, "RELEASE SAVEPOINT run_schedule")
+		if err != nil {
+			return errors.Wrapf(err, "releasing savepoint for schedule %d", schedule.ID)
+		}
+
+		return nil
+	}
+
+	if ctx.Err() != nil {
+		return runErr
+	}
+
+	_, err = tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT run_schedule")
+	if err != nil {
+		return errors.Wrapf(err, "rolling back failed run of schedule %d", schedule.ID)
+	}
+
+	query, args, err := sq.Update("app.notification_schedules").
+		Set("paused", true).
+		Set("last_error", runErr.Error()).
+		Where(sq.Eq{"id": schedule.ID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for pausing failed schedule")
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrapf(err, "pausing failed schedule %d", schedule.ID)
+	}
+
+	return nil
+}
+
+// moveSchedule inserts notifications planned for schedule and moves it to the next run.
+func moveSchedule(
+	ctx context.Context,
+	tx sqlx.ExtContext,
+	schedule *model.Schedule,
+	plan dataprovider.PlanFunc,
+	now time.Time,
+) error {
+	notifications, next, err := plan(schedule, now)
+	if err != nil {
+		return errors.Wrapf(err, "planning schedule %d", schedule.ID)
+	}
+
+	if len(notifications) > 0 {
+		err = insertBatch(ctx, tx, notifications)
+		if err != nil {
+			return err
+		}
+	}
+
+	query, args, err := sq.Update("app.notification_schedules").
+		Set("next_run_at", next).
+		Set("last_run_at", now).
+		Set("paused", next == nil).
+		Where(sq.Eq{"id": schedule.ID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for moving schedule to next run")
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrapf(err, "moving schedule %d to next run", schedule.ID)
+	}
+
+	return nil
+}
+
+func (s *ScheduleStore) exec(ctx context.Context, id int, query string, args []interface{}) error {
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrapf(err, "executing query %s for schedule %d", query, id)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrapf(err, "executing query %s for schedule %d", query, id)
+	}
+
+	if n == 0 {
+		return dataprovider.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/dataprovider"
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+var templateColumns = []string{
+	"type",
+	"title",
+	"body",
+	"created_at",
+	"updated_at",
+}
+
+func NewTemplateStore(db sqlx.ExtContext) *TemplateStore {
+	return &TemplateStore{
+		db: db,
+	}
+}
+
+// TemplateStore is a notification templates postgres store
+type TemplateStore struct {
+	db sqlx.ExtContext
+}
+
+// Insert inserts new template, dataprovider.ErrAlreadyExists is returned if template for the type exists
+func (s *TemplateStore) Insert(ctx context.Context, template *model.Template) error {
+	query, args, err := sq.Insert("app.notification_templates").
+		SetMap(map[string]interface{}{
+			"type":  template.Type,
+			"title": template.Title,
+			"body":  template.Body,
+		}).
+		Suffix("ON CONFLICT (type) DO NOTHING returning created_at, updated_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for inserting template")
+	}
+
+	err = s.db.QueryRowxContext(ctx, query, args...).Scan(&template.CreatedAt, &template.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return dataprovider.ErrAlreadyExists
+	}
+	if err != nil {
+		return errors.Wrapf(err, "inserting template %q", template.Type)
+	}
+
+	return nil
+}
+
+// Get gets template by notification type
+func (s *TemplateStore) Get(ctx context.Context, templateType string) (*model.Template, error) {
+	query, args, err := sq.Select(templateColumns...).
+		From("app.notification_templates").
+		Where(sq.Eq{"type": templateType}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for getting template")
+	}
+
+	template := new(model.Template)
+
+	err = sqlx.GetContext(ctx, s.db, template, query, args...)
+	if err == sql.ErrNoRows {
+		return nil, dataprovider.ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "selecting template %q", templateType)
+	}
+
+	return template, nil
+}
+
+// List gets all templates ordered by type
+func (s *TemplateStore) List(ctx context.Context) ([]*model.Template, error) {
+	templates := make([]*model.Template, 0)
+
+	query, args, err := sq.Select(templateColumns...).
+		From("app.notification_templates").
+		OrderBy("type").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for listing templates")
+	}
+
+	err = sqlx.SelectContext(ctx, s.db, &templates, query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "selecting templates from database with query %s", query)
+	}
+
+	return templates, nil
+}
+
+// Update updates template's title and body
+func (s *TemplateStore) Update(ctx context.Context, template *model.Template) error {
+	query, args, err := sq.Update("app.notification_templates").
+		Set("title", template.Title).
+		Set("body", template.Body).
+		Set("updated_at", sq.Expr("now()")).
+		Where(sq.Eq{"type": template.Type}).
+		Suffix("returning created_at, updated_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for updating template")
+	}
+
+	err = s.db.QueryRowxContext(ctx, query, args...).Scan(&template.CreatedAt, &template.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return dataprovider.ErrNotFound
+	}
+	if err != nil {
+		return errors.Wrapf(err, "updating template %q", template.Type)
+	}
+
+	return nil
+}
+
+// Delete deletes template
+func (s *TemplateStore) Delete(ctx context.Context, templateType string) error {
+	query, args, err := sq.Delete("app.notification_templates").
+		Where(sq.Eq{"type": templateType}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for deleting template")
+	}
+
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrapf(err, "deleting template %q", templateType)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrapf(err, "deleting template %q", templateType)
+	}
+
+	if n == 0 {
+		return dataprovider.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type txBeginner interface {
+	BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error)
+}
+
+// withTx runs fn within transaction, transaction is committed if fn succeeds.
+// If db is not able to begin transaction (e.g. it is transaction already) fn is run against db itself.
+func withTx(ctx context.Context, db sqlx.ExtContext, fn func(tx sqlx.ExtContext) error) error {
+	b, ok := db.(txBeginner)
+	if !ok {
+		return fn(db)
+	}
+
+	tx, err := b.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "beginning transaction")
+	}
+
+	err = fn(tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return errors.Wrap(tx.Commit(), "committing transaction")
+}
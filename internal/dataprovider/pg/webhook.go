@@ -0,0 +1,392 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/dataprovider"
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+var webhookColumns = []string{
+	"id",
+	"url",
+	"events",
+	"types",
+	"secret",
+	"active",
+	"created_at",
+}
+
+var deliveryColumns = []string{
+	"id",
+	"webhook_id",
+	"event",
+	"payload",
+	"status",
+	"attempts",
+	"next_attempt_at",
+	"created_at",
+}
+
+var attemptColumns = []string{
+	"a.id",
+	"a.delivery_id",
+	"a.attempt",
+	"a.status_code",
+	"a.error",
+	"a.duration_ms",
+	"a.at",
+}
+
+func NewWebhookStore(db sqlx.ExtContext) *WebhookStore {
+	return &WebhookStore{
+		db: db,
+	}
+}
+
+// WebhookStore is a webhooks and webhook deliveries postgres store
+type WebhookStore struct {
+	db sqlx.ExtContext
+}
+
+// webhook is a row of app.webhooks, events and types filters are stored as jsonb
+type webhook struct {
+	model.Webhook
+	RawEvents []byte `db:"events"`
+	RawTypes  []byte `db:"types"`
+}
+
+func (w *webhook) decode() (*model.Webhook, error) {
+	err := json.Unmarshal(w.RawEvents, &w.Events)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decoding events of webhook %d", w.ID)
+	}
+
+	err = json.Unmarshal(w.RawTypes, &w.Types)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decoding types of webhook %d", w.ID)
+	}
+
+	return &w.Webhook, nil
+}
+
+// Insert inserts new webhook
+func (s *WebhookStore) Insert(ctx context.Context, webhook *model.Webhook) error {
+	query, args, err := sq.Insert("app.webhooks").
+		SetMap(webhookValues(webhook)).
+		Suffix("returning id, created_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for inserting webhook")
+	}
+
+	err = s.db.QueryRowxContext(ctx, query, args...).Scan(&webhook.ID, &webhook.CreatedAt)
+	if err != nil {
+		return errors.Wrap(err, "can't scan webhook id")
+	}
+
+	return nil
+}
+
+// Get gets webhook by id
+func (s *WebhookStore) Get(ctx context.Context, id int) (*model.Webhook, error) {
+	query, args, err := sq.Select(webhookColumns...).
+		From("app.webhooks").
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for getting webhook")
+	}
+
+	row := new(webhook)
+
+	err = sqlx.GetContext(ctx, s.db, row, query, args...)
+	if err == sql.ErrNoRows {
+		return nil, dataprovider.ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "selecting webhook %d", id)
+	}
+
+	return row.decode()
+}
+
+// List gets all webhooks
+func (s *WebhookStore) List(ctx context.Context) ([]*model.Webhook, error) {
+	query, args, err := sq.Select(webhookColumns...).
+		From("app.webhooks").
+		OrderBy("id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for listing webhooks")
+	}
+
+	rows := make([]*webhook, 0)
+
+	err = sqlx.SelectContext(ctx, s.db, &rows, query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "selecting webhooks from database with query %s", query)
+	}
+
+	webhooks := make([]*model.Webhook, 0, len(rows))
+	for _, row := range rows {
+		w, err := row.decode()
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+
+	return webhooks, nil
+}
+
+// Update replaces webhook's url, filters and state, secret is replaced when it is set
+func (s *WebhookStore) Update(ctx context.Context, webhook *model.Webhook) error {
+	values := webhookValues(webhook)
+	if webhook.Secret == "" {
+		delete(values, "secret")
+	}
+
+	query, args, err := sq.Update("app.webhooks").
+		SetMap(values).
+		Where(sq.Eq{"id": webhook.ID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for updating webhook")
+	}
+
+	return s.exec(ctx, webhook.ID, query, args)
+}
+
+// Delete deletes webhook along with its deliveries
+func (s *WebhookStore) Delete(ctx context.Context, id int) error {
+	query, args, err := sq.Delete("app.webhooks").
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for deleting webhook")
+	}
+
+	return s.exec(ctx, id, query, args)
+}
+
+// InsertDeliveries inserts pending deliveries
+func (s *WebhookStore) InsertDeliveries(ctx context.Context, deliveries []*model.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	insert := sq.Insert("app.webhook_deliveries").
+		Columns("webhook_id", "event", "payload", "status", "next_attempt_at")
+
+	for _, d := range deliveries {
+		insert = insert.Values(d.WebhookID, d.Event, string(d.Payload), d.Status, d.NextAttemptAt)
+	}
+
+	query, args, err := insert.
+		Suffix("returning id, created_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "creating sql query for inserting webhook deliveries")
+	}
+
+	rows, err := s.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrap(err, "inserting webhook deliveries")
+	}
+	defer rows.Close()
+
+	i := 0
+	for rows.Next() && i < len(deliveries) {
+		err = rows.Scan(&deliveries[i].ID, &deliveries[i].CreatedAt)
+		if err != nil {
+			return errors.Wrap(err, "can't scan webhook delivery id")
+		}
+		i++
+	}
+
+	return errors.Wrap(rows.Err(), "inserting webhook deliveries")
+}
+
+// ListDeliveries gets up to limit latest deliveries of webhook
+func (s *WebhookStore) ListDeliveries(ctx context.Context, webhookID int, limit int) ([]*model.WebhookDelivery, error) {
+	deliveries := make([]*model.WebhookDelivery, 0, limit)
+
+	query, args, err := sq.Select(deliveryColumns...).
+		From("app.webhook_deliveries").
+		Where(sq.Eq{"webhook_id": webhookID}).
+		OrderBy("id DESC").
+		Limit(uint64(limit)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for listing webhook deliveries")
+	}
+
+	err = sqlx.SelectContext(ctx, s.db, &deliveries, query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "selecting webhook deliveries from database with query %s", query)
+	}
+
+	return deliveries, nil
+}
+
+// ListAttempts gets all attempts of webhook's delivery
+func (s *WebhookStore) ListAttempts(ctx context.Context, webhookID int, deliveryID int) ([]*model.WebhookAttempt, error) {
+	attempts := make([]*model.WebhookAttempt, 0)
+
+	query, args, err := sq.Select(attemptColumns...).
+		From("app.webhook_attempts a").
+		Join("app.webhook_deliveries d ON d.id = a.delivery_id").
+		Where(sq.Eq{"d.id": deliveryID, "d.webhook_id": webhookID}).
+		OrderBy("a.attempt").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for listing webhook attempts")
+	}
+
+	err = sqlx.SelectContext(ctx, s.db, &attempts, query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "selecting webhook attempts from database with query %s", query)
+	}
+
+	return attempts, nil
+}
+
+// ClaimDue claims due pending deliveries by moving their next attempt time to the end of lease.
+// Rows are locked with SKIP LOCKED so several instances may deliver concurrently.
+func (s *WebhookStore) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*model.WebhookDelivery, error) {
+	deliveries := make([]*model.WebhookDelivery, 0, limit)
+
+	now := time.Now()
+
+	due, dueArgs, err := sq.Select("id").
+		From("app.webhook_deliveries").
+		Where(sq.Eq{"status": model.DeliveryPending}).
+		Where(sq.LtOrEq{"next_attempt_at": now}).
+		OrderBy("next_attempt_at", "id").
+		Limit(uint64(limit)).
+		Suffix("FOR UPDATE SKIP LOCKED").
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for selecting due webhook deliveries")
+	}
+
+	query, args, err := sq.Update("app.webhook_deliveries").
+		Set("next_attempt_at", now.Add(lease)).
+		Where(sq.Expr("id IN ("+due+")", dueArgs...)).
+		Suffix("returning " + strings.Join(deliveryColumns, ", ")).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating sql query for claiming due webhook deliveries")
+	}
+
+	err = sqlx.SelectContext(ctx, s.db, &deliveries, query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "claiming due webhook deliveries with query %s", query)
+	}
+
+	return deliveries, nil
+}
+
+// RecordAttempt inserts attempt and updates delivery in single transaction
+func (s *WebhookStore) RecordAttempt(
+	ctx context.Context,
+	delivery *model.WebhookDelivery,
+	attempt *model.WebhookAttempt,
+) error {
+	return withTx(ctx, s.db, func(tx sqlx.ExtContext) error {
+		query, args, err := sq.Insert("app.webhook_attempts").
+			SetMap(map[string]interface{}{
+				"delivery_id": attempt.DeliveryID,
+				"attempt":     attempt.Attempt,
+				"status_code": attempt.StatusCode,
+				"error":       attempt.Error,
+				"duration_ms": attempt.DurationMs,
+				"at":          attempt.At,
+			}).
+			Suffix("returning id").
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			return errors.Wrap(err, "creating sql query for inserting webhook attempt")
+		}
+
+		err = tx.QueryRowxContext(ctx, query, args...).Scan(&attempt.ID)
+		if err != nil {
+			return errors.Wrap(err, "can't scan webhook attempt id")
+		}
+
+		query, args, err = sq.Update("app.webhook_deliveries").
+			Set("status", delivery.Status).
+			Set("attempts", delivery.Attempts).
+			Set("next_attempt_at", delivery.NextAttemptAt).
+			Where(sq.Eq{"id": delivery.ID}).
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			return errors.Wrap(err, "creating sql query for updating webhook delivery")
+		}
+
+		_, err = tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return errors.Wrapf(err, "updating webhook delivery %d", delivery.ID)
+		}
+
+		return nil
+	})
+}
+
+func (s *WebhookStore) exec(ctx context.Context, id int, query string, args []interface{}) error {
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrapf(err, "executing query %s for webhook %d", query, id)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrapf(err, "executing query %s for webhook %d", query, id)
+	}
+
+	if n == 0 {
+		return dataprovider.ErrNotFound
+	}
+
+	return nil
+}
+
+func webhookValues(webhook *model.Webhook) map[string]interface{} {
+	return map[string]interface{}{
+		"url":    webhook.URL,
+		"events": jsonList(webhook.Events),
+		"types":  jsonList(webhook.Types),
+		"secret": webhook.Secret,
+		"active": webhook.Active,
+	}
+}
+
+// jsonList encodes list as json array, nil list is encoded as empty array
+func jsonList(list []string) string {
+	if list == nil {
+		list = []string{}
+	}
+
+	b, _ := json.Marshal(list)
+	return string(b)
+}
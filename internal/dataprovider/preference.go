@@ -0,0 +1,17 @@
+package dataprovider
+
+import (
+	"context"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+type PreferenceStore interface {
+	ListByUser(ctx context.Context, userID int64) ([]*model.Preference, error)
+	// Replace replaces all user's preferences.
+	Replace(ctx context.Context, userID int64, preferences []*model.Preference) error
+	// GetQuietHours returns nil when user has no quiet hours.
+	GetQuietHours(ctx context.Context, userID int64) (*model.QuietHours, error)
+	SetQuietHours(ctx context.Context, userID int64, quietHours *model.QuietHours) error
+	DeleteQuietHours(ctx context.Context, userID int64) error
+}
@@ -0,0 +1,21 @@
+package dataprovider
+
+import (
+	"context"
+	"time"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+// PlanFunc returns notifications that due schedule produces at now along with schedule's next run time.
+type PlanFunc func(schedule *model.Schedule, now time.Time) ([]*model.Notification, *time.Time, error)
+
+type ScheduleStore interface {
+	Insert(ctx context.Context, schedule *model.Schedule) error
+	Get(ctx context.Context, id int) (*model.Schedule, error)
+	List(ctx context.Context) ([]*model.Schedule, error)
+	Update(ctx context.Context, schedule *model.Schedule) error
+	SetPaused(ctx context.Context, id int, paused bool, nextRunAt *time.Time) error
+	Delete(ctx context.Context, id int) error
+	RunDue(ctx context.Context, limit int, plan PlanFunc) (int, error)
+}
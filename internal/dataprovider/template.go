@@ -0,0 +1,15 @@
+package dataprovider
+
+import (
+	"context"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+type TemplateStore interface {
+	Insert(ctx context.Context, template *model.Template) error
+	Get(ctx context.Context, templateType string) (*model.Template, error)
+	List(ctx context.Context) ([]*model.Template, error)
+	Update(ctx context.Context, template *model.Template) error
+	Delete(ctx context.Context, templateType string) error
+}
@@ -0,0 +1,24 @@
+package dataprovider
+
+import (
+	"context"
+	"time"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+type WebhookStore interface {
+	Insert(ctx context.Context, webhook *model.Webhook) error
+	Get(ctx context.Context, id int) (*model.Webhook, error)
+	List(ctx context.Context) ([]*model.Webhook, error)
+	Update(ctx context.Context, webhook *model.Webhook) error
+	Delete(ctx context.Context, id int) error
+	InsertDeliveries(ctx context.Context, deliveries []*model.WebhookDelivery) error
+	ListDeliveries(ctx context.Context, webhookID int, limit int) ([]*model.WebhookDelivery, error)
+	ListAttempts(ctx context.Context, webhookID int, deliveryID int) ([]*model.WebhookAttempt, error)
+	// ClaimDue claims up to limit pending deliveries which attempt time has come,
+	// claimed deliveries are not claimed again until lease expires.
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*model.WebhookDelivery, error)
+	// RecordAttempt stores attempt and delivery's new status, attempts counter and next attempt time.
+	RecordAttempt(ctx context.Context, delivery *model.WebhookDelivery, attempt *model.WebhookAttempt) error
+}
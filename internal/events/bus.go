@@ -0,0 +1,99 @@
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+var (
+	// ErrSlowConsumer is reported by subscription that was closed because its buffer overflowed.
+	ErrSlowConsumer = errors.New("subscriber is too slow")
+)
+
+// NewBus creates in-process events bus.
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Bus fans out published events to all subscribers of the process.
+// Publishing never blocks, subscriber that can't keep up is unsubscribed.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription receives events through C, C is closed when subscription is closed.
+type Subscription struct {
+	C <-chan *model.Event
+
+	c   chan *model.Event
+	bus *Bus
+	err error
+}
+
+// Subscribe creates subscription with buffer of size events.
+func (b *Bus) Subscribe(size int) *Subscription {
+	c := make(chan *model.Event, size)
+	s := &Subscription{
+		C:   c,
+		c:   c,
+		bus: b,
+	}
+
+	b.mu.Lock()
+	b.subscribers[s] = struct{}{}
+	b.mu.Unlock()
+
+	return s
+}
+
+// Publish implements service.EventPublisher.
+func (b *Bus) Publish(_ context.Context, event *model.Event) error {
+	var slow []*Subscription
+
+	b.mu.RLock()
+	for s := range b.subscribers {
+		select {
+		case s.c <- event:
+		default:
+			slow = append(slow, s)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, s := range slow {
+		b.unsubscribe(s, ErrSlowConsumer)
+	}
+
+	return nil
+}
+
+// Close unsubscribes subscription, it is safe to call Close several times.
+func (s *Subscription) Close() {
+	s.bus.unsubscribe(s, nil)
+}
+
+// Err returns reason why subscription was closed by bus.
+// It must be called after C is closed.
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+func (b *Bus) unsubscribe(s *Subscription, reason error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[s]; !ok {
+		return
+	}
+
+	delete(b.subscribers, s)
+	s.err = reason
+	close(s.c)
+}
@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// Delivery channels.
+const (
+	ChannelEmail = "email"
+	ChannelPush  = "push"
+)
+
+// DeliveryCollected is a status of delivery collected to user's digest, it is sent within digest.
+const DeliveryCollected = "collected"
+
+// Delivery is a delivery of notification to user through channel (email, push...).
+// Statuses of deliveries are the same as of webhook deliveries, except DeliveryCollected.
+type Delivery struct {
+	ID             int    `json:"id" db:"id"`
+	NotificationID int    `json:"notification_id" db:"notification_id"`
+	UserID         int64  `json:"user_id" db:"user_id"`
+	Channel        string `json:"channel" db:"channel"`
+	Status         string `json:"status" db:"status"`
+	Attempts       int    `json:"attempts" db:"attempts"`
+	// Error is an error of the latest failed attempt.
+	Error string `json:"error,omitempty" db:"error"`
+	// NextAttemptAt is nil when delivery is finished.
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty" db:"sent_at"`
+	// DigestedAt is set when delivery is sent within digest.
+	DigestedAt *time.Time `json:"digested_at,omitempty" db:"digested_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
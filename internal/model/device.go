@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// Push platforms.
+const (
+	PlatformFCM  = "fcm"
+	PlatformAPNs = "apns"
+)
+
+// Device is a user's device registered to receive push notifications.
+type Device struct {
+	ID        int       `json:"id" db:"id"`
+	UserID    int64     `json:"-" db:"user_id"`
+	Platform  string    `json:"platform" db:"platform"`
+	Token     string    `json:"token" db:"token"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// Digest frequencies.
+const (
+	DigestHourly = "hourly"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// DigestType is a type of digest notifications, template of this type replaces built-in digest template.
+// Template variables are "count", "items" and "frequency".
+const DigestType = "digest"
+
+// Digest is user's setting to receive notifications through channel as periodic summary instead of one by one.
+// Notifications of priority up to MaxPriority are collected and sent together at NextAt.
+type Digest struct {
+	UserID    int64  `json:"-" db:"user_id"`
+	Frequency string `json:"frequency" db:"frequency"`
+	Channel   string `json:"channel" db:"channel"`
+	// MaxPriority is the highest priority of collected notifications.
+	MaxPriority Priority `json:"max_priority" db:"max_priority"`
+	// Timezone is IANA timezone name, daily and weekly digests are sent at the same local time.
+	Timezone string `json:"timezone" db:"timezone"`
+	// NextAt is a time of the next digest, it is set by controller.
+	NextAt time.Time `json:"next_at" db:"next_at"`
+}
+
+// DigestPreview is the next digest as it would be sent now.
+type DigestPreview struct {
+	Digest *Digest `json:"digest"`
+	// Notification is a rendered summary, it is nil when nothing is collected.
+	Notification *Notification   `json:"notification"`
+	Items        []*Notification `json:"items"`
+}
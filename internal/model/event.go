@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// Event types.
+const (
+	EventNotificationPublished = "notification.published"
+)
+
+// Event describes change of notification that is delivered to subscribers.
+type Event struct {
+	Type         string        `json:"type"`
+	Notification *Notification `json:"notification"`
+	At           time.Time     `json:"at"`
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// Idempotency key statuses.
+const (
+	// IdempotencyInFlight is a status of key which request is being processed.
+	IdempotencyInFlight = "in_flight"
+	// IdempotencyCompleted is a status of key which response is stored.
+	IdempotencyCompleted = "completed"
+)
+
+// IdempotencyKey is a key of user's request along with snapshot of its response.
+// Requests with the same key are processed once while key is not expired.
+type IdempotencyKey struct {
+	UserID int64  `db:"user_id"`
+	Key    string `db:"key"`
+	// RequestHash identifies request, the same key can not be reused for different request.
+	RequestHash string    `db:"request_hash"`
+	Status      string    `db:"status"`
+	StatusCode  int       `db:"status_code"`
+	ContentType string    `db:"content_type"`
+	Response    []byte    `db:"response"`
+	CreatedAt   time.Time `db:"created_at"`
+	// ExpiresAt is a time key may be reused, in-flight key expires if its request is not completed in time.
+	ExpiresAt time.Time `db:"expires_at"`
+}
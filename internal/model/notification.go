@@ -2,12 +2,130 @@ package model
 
 import "time"
 
+// Notification visibility states relative to FromTime/TillTime window.
+const (
+	NotificationStateActive  = "active"
+	NotificationStateExpired = "expired"
+	NotificationStatePending = "pending"
+	NotificationStateRevoked = "revoked"
+	// NotificationStateScheduled is a state of notification which publishing time has not come yet.
+	NotificationStateScheduled = "scheduled"
+)
+
 type Notification struct {
-	ID       int        `json:"id"`
-	UserID   *int64     `json:"-"`
-	Title    string     `json:"title"`
-	Body     string     `json:"body"`
-	Type     string     `json:"type"`
-	FromTime *time.Time `json:"-"`
-	TillTime *time.Time `json:"-"`
+	ID        int        `json:"id" db:"id"`
+	UserID    *int64     `json:"-" db:"user_id"`
+	Title     string     `json:"title" db:"title"`
+	Body      string     `json:"body" db:"body"`
+	Type      string     `json:"type" db:"type"`
+	Priority  Priority   `json:"priority" db:"priority"`
+	FromTime  *time.Time `json:"from_time,omitempty" db:"from_time"`
+	TillTime  *time.Time `json:"till_time,omitempty" db:"till_time"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	// PublishAt is a time notification should be published at, nil means immediately.
+	PublishAt   *time.Time `json:"publish_at,omitempty" db:"publish_at"`
+	PublishedAt *time.Time `json:"published_at,omitempty" db:"published_at"`
+	// PublishSeq is a position of published notification in stream, it is assigned by database on publishing.
+	PublishSeq *int64     `json:"-" db:"publish_seq"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	// Version is incremented on every change of notification.
+	Version int `json:"version" db:"version"`
+
+	// CollapseKey identifies repeated notifications, notification with the same key and recipient
+	// as active notification is merged into it instead of being stored separately.
+	CollapseKey string `json:"collapse_key,omitempty" db:"collapse_key"`
+	// CollapseCount is a number of notifications merged into this one including itself.
+	CollapseCount int `json:"collapse_count" db:"collapse_count"`
+	// LastSeenAt is a time the latest notification was merged into this one.
+	LastSeenAt time.Time `json:"last_seen_at" db:"last_seen_at"`
+
+	// Language is a text search configuration title and body are indexed with, e.g. "english".
+	Language string `json:"language" db:"language"`
+
+	// Translations holds per locale variants of title and body.
+	Translations map[string]NotificationContent `json:"translations,omitempty" db:"-"`
+	// Locale of title and body, it is set when notification is localized for the user.
+	Locale string `json:"locale,omitempty" db:"-"`
+
+	// Read status is filled only for notifications requested on behalf of the user.
+	Read   bool       `json:"read" db:"read"`
+	ReadAt *time.Time `json:"read_at,omitempty" db:"read_at"`
+}
+
+// VisibleTo reports whether published notification is shown to the user at time t.
+func (n *Notification) VisibleTo(userID int64, t time.Time) bool {
+	if n.UserID != nil && *n.UserID != userID {
+		return false
+	}
+
+	if n.RevokedAt != nil || n.PublishedAt == nil {
+		return false
+	}
+
+	if n.FromTime != nil && n.FromTime.After(t) {
+		return false
+	}
+
+	return n.TillTime == nil || n.TillTime.After(t)
+}
+
+// NotificationContent is a localized variant of notification's title and body.
+type NotificationContent struct {
+	Title string `json:"title" db:"title"`
+	Body  string `json:"body" db:"body"`
+}
+
+// NotificationPatch describes partial notification update,
+// nil fields and not set times are left unchanged. Version must match current notification's version.
+type NotificationPatch struct {
+	Title    *string
+	Body     *string
+	Type     *string
+	Priority *Priority
+	FromTime TimePatch
+	TillTime TimePatch
+	Version  int
+}
+
+// TimePatch is a change of optional time, time is changed only if Set, nil Time clears it.
+type TimePatch struct {
+	Set  bool
+	Time *time.Time
+}
+
+// NotificationFilter describes page of notifications for administrative listings.
+// Empty State matches notifications in any state.
+// Listing is ordered by (created_at, id), so only CreatedAt and ID of cursor are used,
+// nil cursor means first page.
+type NotificationFilter struct {
+	UserID *int64
+	State  string
+	After  *NotificationCursor
+	Limit  int
+}
+
+// NotificationCursor points to a notification's position in listing ordered by (priority, created_at, id).
+type NotificationCursor struct {
+	Priority  Priority
+	CreatedAt time.Time
+	ID        int
+}
+
+// StreamPosition points to a notification's position in stream of published notifications
+// ordered by publish_seq.
+type StreamPosition struct {
+	PublishSeq int64
+}
+
+// InboxFilter describes page of user's notifications.
+// CreatedFrom and CreatedTill bound creation time, nil Read matches both read and unread notifications.
+// Only notifications positioned after cursor are selected, nil cursor means first page.
+type InboxFilter struct {
+	Types       []string
+	Priorities  []Priority
+	CreatedFrom *time.Time
+	CreatedTill *time.Time
+	Read        *bool
+	After       *NotificationCursor
+	Limit       int
 }
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// OutboxEntry is an event stored along with notification change and published later by relay.
+// Statuses of entries are the same as of webhook deliveries.
+type OutboxEntry struct {
+	ID             int64  `json:"id" db:"id"`
+	Event          string `json:"event" db:"event"`
+	NotificationID int    `json:"notification_id" db:"notification_id"`
+	Status         string `json:"status" db:"status"`
+	Attempts       int    `json:"attempts" db:"attempts"`
+	// Error is an error of the latest failed attempt.
+	Error string `json:"error,omitempty" db:"error"`
+	// NextAttemptAt is nil when entry is published or failed.
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	PublishedAt   *time.Time `json:"published_at,omitempty" db:"published_at"`
+}
+
+// OutboxFilter describes selection of outbox entries, empty Status matches entries in any status.
+// Entries are listed from the latest, BeforeID limits them to entries older than given one.
+type OutboxFilter struct {
+	Status   string
+	BeforeID int64
+	Limit    int
+}
+
+// OutboxStats describes backlog of outbox.
+type OutboxStats struct {
+	Pending int `db:"pending"`
+	Failed  int `db:"failed"`
+	// OldestPendingAt is a creation time of the oldest pending entry, it is nil when there are none.
+	OldestPendingAt *time.Time `db:"oldest_pending_at"`
+}
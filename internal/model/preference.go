@@ -0,0 +1,31 @@
+package model
+
+// ChannelInbox is a channel of in-app inbox and live streams, it is not a delivery channel.
+const ChannelInbox = "inbox"
+
+// Preference tells whether user receives notifications of type through channel.
+type Preference struct {
+	Type    string `json:"type" db:"type"`
+	Channel string `json:"channel" db:"channel"`
+	Enabled bool   `json:"enabled" db:"enabled"`
+}
+
+// Preferences are user's preferences along with mandatory types that can't be disabled.
+type Preferences struct {
+	Preferences    []*Preference `json:"preferences"`
+	MandatoryTypes []string      `json:"mandatory_types"`
+	// QuietHours is nil when user has no quiet hours.
+	QuietHours *QuietHours `json:"quiet_hours"`
+	// Digest is nil when user receives notifications one by one.
+	Digest *Digest `json:"digest"`
+}
+
+// QuietHours is a daily window in user's timezone when non-critical deliveries through channels
+// are deferred to its end. Window ends on the next day when End is not after Start, e.g. 22:00-07:00.
+type QuietHours struct {
+	// Timezone is IANA timezone name.
+	Timezone string `json:"timezone" db:"timezone"`
+	// Start and End are local times in HH:MM format.
+	Start string `json:"start" db:"start_time"`
+	End   string `json:"end" db:"end_time"`
+}
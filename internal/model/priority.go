@@ -0,0 +1,71 @@
+package model
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// Priority of notification, higher priority notifications go first in inbox.
+type Priority string
+
+const (
+	PriorityLow      Priority = "low"
+	PriorityNormal   Priority = "normal"
+	PriorityHigh     Priority = "high"
+	PriorityCritical Priority = "critical"
+)
+
+// priorities are ordered by rank, rank is stored in database
+var priorities = []Priority{
+	PriorityLow,
+	PriorityNormal,
+	PriorityHigh,
+	PriorityCritical,
+}
+
+// Valid reports whether p is known priority.
+func (p Priority) Valid() bool {
+	return p.rank() >= 0
+}
+
+// BypassesMutes reports whether notification of priority p must be delivered
+// regardless of user's mutes and quiet hours.
+func (p Priority) BypassesMutes() bool {
+	return p == PriorityCritical
+}
+
+// AtMost reports whether priority p is not higher than q.
+func (p Priority) AtMost(q Priority) bool {
+	return p.rank() <= q.rank()
+}
+
+func (p Priority) rank() int {
+	for i := range priorities {
+		if priorities[i] == p {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Value implements driver.Valuer, priority is stored as its rank.
+func (p Priority) Value() (driver.Value, error) {
+	r := p.rank()
+	if r < 0 {
+		return nil, fmt.Errorf("unknown priority %q", string(p))
+	}
+
+	return int64(r), nil
+}
+
+// Scan implements sql.Scanner.
+func (p *Priority) Scan(src interface{}) error {
+	r, ok := src.(int64)
+	if !ok || r < 0 || int(r) >= len(priorities) {
+		return fmt.Errorf("can't scan priority from %v", src)
+	}
+
+	*p = priorities[r]
+	return nil
+}
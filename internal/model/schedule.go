@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// Catch-up policies define how runs missed while service was down are handled.
+const (
+	// CatchUpSkip produces notification for the latest missed run only.
+	CatchUpSkip = "skip"
+	// CatchUpAll produces notification for every missed run, number of runs is limited by configuration.
+	CatchUpAll = "all"
+)
+
+// Schedule produces notifications according to cron expression evaluated in schedule's timezone.
+type Schedule struct {
+	ID       int    `json:"id" db:"id"`
+	Cron     string `json:"cron" db:"cron"`
+	Timezone string `json:"timezone" db:"timezone"`
+	CatchUp  string `json:"catch_up" db:"catch_up"`
+
+	UserID   *int64   `json:"user_id,omitempty" db:"user_id"`
+	Type     string   `json:"type" db:"type"`
+	Title    string   `json:"title" db:"title"`
+	Body     string   `json:"body" db:"body"`
+	Priority Priority `json:"priority" db:"priority"`
+
+	Paused bool `json:"paused" db:"paused"`
+	// LastError is a reason schedule was paused by failed run, it is cleared when schedule is resumed.
+	LastError string `json:"last_error,omitempty" db:"last_error"`
+	// NextRunAt is nil when cron expression has no more activations.
+	NextRunAt *time.Time `json:"next_run_at,omitempty" db:"next_run_at"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty" db:"last_run_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,27 @@
+package model
+
+// SearchFilter describes page of full-text search results.
+// Query is in web search syntax: quoted phrases, "or" and "-" for exclusion are supported.
+// Only results positioned after cursor are selected, nil cursor means first page.
+type SearchFilter struct {
+	Query string
+	// Language is a text search configuration query is parsed with, e.g. "english".
+	Language string
+	After    *SearchCursor
+	Limit    int
+}
+
+// SearchCursor points to a result's position in search results ordered by (rank, id).
+type SearchCursor struct {
+	Rank float64
+	ID   int
+}
+
+// SearchResult is a notification matching search query along with its rank and highlighted snippets.
+type SearchResult struct {
+	Notification
+	Rank float64 `json:"rank" db:"rank"`
+	// Headline holds title and fragments of body as HTML: text is escaped
+	// and matches are wrapped into <mark> tags.
+	Headline NotificationContent `json:"headline" db:"headline"`
+}
@@ -1,5 +1,8 @@
 package model
 
 type Session struct {
-	UserID int64
+	UserID  int64
+	IsAdmin bool `json:"is_admin"`
+	// Locale is user's preferred locale from the profile.
+	Locale string `json:"locale"`
 }
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Template is a notification template keyed by notification type.
+// Title and Body are text/template sources, variables are referenced as {{.name}}.
+type Template struct {
+	Type      string    `json:"type" db:"type"`
+	Title     string    `json:"title" db:"title"`
+	Body      string    `json:"body" db:"body"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
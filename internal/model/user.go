@@ -5,4 +5,24 @@ type User struct {
 	Email     string `json:"email"`
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
+	// Locales are user's preferred locales, most preferred goes first.
+	Locales []string `json:"-"`
+	// MutedTypes are notification types user disabled in inbox, they are set by controller.
+	MutedTypes []string `json:"-"`
+}
+
+// Mutes reports whether user disabled notifications of n's type in inbox,
+// notifications which priority bypasses mutes are never muted.
+func (u *User) Mutes(n *Notification) bool {
+	if n.Priority.BypassesMutes() {
+		return false
+	}
+
+	for _, t := range u.MutedTypes {
+		if t == n.Type {
+			return true
+		}
+	}
+
+	return false
 }
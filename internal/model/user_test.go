@@ -0,0 +1,28 @@
+package model
+
+import "testing"
+
+func TestUserMutes(t *testing.T) {
+	tests := []struct {
+		name     string
+		muted    []string
+		n        *Notification
+		expected bool
+	}{
+		{"no muted types", nil, &Notification{Type: "news", Priority: PriorityNormal}, false},
+		{"empty muted types", []string{}, &Notification{Type: "news", Priority: PriorityLow}, false},
+		{"muted type", []string{"promo", "news"}, &Notification{Type: "news", Priority: PriorityNormal}, true},
+		{"other type", []string{"promo"}, &Notification{Type: "news", Priority: PriorityHigh}, false},
+		{"critical bypasses mute", []string{"news"}, &Notification{Type: "news", Priority: PriorityCritical}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &User{ID: 1, MutedTypes: tt.muted}
+
+			if muted := u.Mutes(tt.n); muted != tt.expected {
+				t.Fatalf("expected muted %v, got %v", tt.expected, muted)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook is a subscription of external system to notification events.
+type Webhook struct {
+	ID  int    `json:"id" db:"id"`
+	URL string `json:"url" db:"url"`
+	// Events are event types webhook is subscribed to, empty list matches all events.
+	Events []string `json:"events" db:"-"`
+	// Types are notification types webhook is subscribed to, empty list matches all types.
+	Types []string `json:"types" db:"-"`
+	// Secret signs deliveries, it is never returned after webhook is created.
+	Secret    string    `json:"-" db:"secret"`
+	Active    bool      `json:"active" db:"active"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Matches reports whether webhook is subscribed to event.
+func (w *Webhook) Matches(event *Event) bool {
+	if !w.Active {
+		return false
+	}
+
+	return contains(w.Events, event.Type) && contains(w.Types, event.Notification.Type)
+}
+
+// WebhookDelivery is a delivery of single event to webhook, it is retried until it succeeds
+// or attempts are exhausted.
+type WebhookDelivery struct {
+	ID        int             `json:"id" db:"id"`
+	WebhookID int             `json:"webhook_id" db:"webhook_id"`
+	Event     string          `json:"event" db:"event"`
+	Payload   json.RawMessage `json:"payload" db:"payload"`
+	Status    string          `json:"status" db:"status"`
+	Attempts  int             `json:"attempts" db:"attempts"`
+	// NextAttemptAt is nil when delivery is finished.
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
+
+// WebhookAttempt is a result of single attempt to deliver event.
+type WebhookAttempt struct {
+	ID         int       `json:"id" db:"id"`
+	DeliveryID int       `json:"delivery_id" db:"delivery_id"`
+	Attempt    int       `json:"attempt" db:"attempt"`
+	StatusCode int       `json:"status_code,omitempty" db:"status_code"`
+	Error      string    `json:"error,omitempty" db:"error"`
+	DurationMs int64     `json:"duration_ms" db:"duration_ms"`
+	At         time.Time `json:"at" db:"at"`
+}
+
+func contains(list []string, s string) bool {
+	if len(list) == 0 {
+		return true
+	}
+
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+var (
+	// ErrUndeliverable error is returned by channel when notification can never be delivered to user,
+	// e.g. user has no address for the channel or address is rejected. Such deliveries are not retried.
+	ErrUndeliverable = errors.New("notification can not be delivered to user")
+)
+
+// Channel interface provides method to deliver notification to user through external medium.
+// Notification is already localized for the user.
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, user *model.User, notification *model.Notification) error
+}
+
+// Channels is a set of available delivery channels.
+type Channels []Channel
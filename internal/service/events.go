@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+// EventPublisher interface provides method to deliver notification events to subscribers.
+type EventPublisher interface {
+	Publish(ctx context.Context, event *model.Event) error
+}
+
+// EventPublishers publishes every event to all publishers.
+type EventPublishers []EventPublisher
+
+// Publish implements EventPublisher, the first error of publishers is returned.
+func (ps EventPublishers) Publish(ctx context.Context, event *model.Event) error {
+	var err error
+
+	for _, p := range ps {
+		if perr := p.Publish(ctx, event); perr != nil && err == nil {
+			err = perr
+		}
+	}
+
+	return err
+}
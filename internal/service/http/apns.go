@@ -0,0 +1,180 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/hummerd/gophercon/internal/model"
+	"github.com/hummerd/gophercon/internal/service"
+)
+
+// apnsTokenTTL is a lifetime of provider token, APNs rejects tokens older than an hour
+const apnsTokenTTL = 50 * time.Minute
+
+// NewAPNsProvider creates Apple Push Notification service provider authenticated by token signing key.
+func NewAPNsProvider(baseURL, keyPath, keyID, teamID, topic string) (*APNsProvider, error) {
+	data, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading apns key %s", keyPath)
+	}
+
+	key, err := parsePrivateKey(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing apns key")
+	}
+
+	return &APNsProvider{
+		client:  newCustomClient(withServicename("apns")),
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		key:     key,
+		keyID:   keyID,
+		teamID:  teamID,
+		topic:   topic,
+	}, nil
+}
+
+// APNsProvider implements service.PushProvider interface over APNs HTTP/2 API.
+type APNsProvider struct {
+	client  *httpClient
+	baseURL string
+	key     crypto.Signer
+	keyID   string
+	teamID  string
+	topic   string
+
+	mu       sync.Mutex
+	jwt      string
+	issuedAt time.Time
+}
+
+type apnsPayload struct {
+	APS            apnsAPS `json:"aps"`
+	NotificationID int     `json:"notification_id"`
+	Type           string  `json:"type"`
+}
+
+type apnsAPS struct {
+	Alert apnsAlert `json:"alert"`
+	Sound string    `json:"sound,omitempty"`
+}
+
+type apnsAlert struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// Platform implements service.PushProvider.
+func (p *APNsProvider) Platform() string {
+	return model.PlatformAPNs
+}
+
+// Push sends notification to device token, token rejected by APNs is reported as service.ErrInvalidToken.
+func (p *APNsProvider) Push(ctx context.Context, token string, notification *model.Notification) error {
+	jwt, err := p.token()
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(&apnsPayload{
+		APS: apnsAPS{
+			Alert: apnsAlert{
+				Title: notification.Title,
+				Body:  notification.Body,
+			},
+			Sound: "default",
+		},
+		NotificationID: notification.ID,
+		Type:           notification.Type,
+	})
+	if err != nil {
+		return errors.Wrap(err, "encoding apns payload")
+	}
+
+	priority := "5"
+	if notification.Priority == model.PriorityHigh || notification.Priority == model.PriorityCritical {
+		priority = "10"
+	}
+
+	u := p.baseURL + "/3/device/" + token
+
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "creating request")
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "bearer "+jwt)
+	req.Header.Set("apns-topic", p.topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", priority)
+	if notification.TillTime != nil {
+		req.Header.Set("apns-expiration", strconv.FormatInt(notification.TillTime.Unix(), 10))
+	}
+
+	resp, err := p.client.Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer drainReader(resp.Body, zerolog.Ctx(ctx))
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var apnsErr struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&apnsErr)
+
+	switch {
+	case resp.StatusCode == http.StatusGone,
+		apnsErr.Reason == "BadDeviceToken",
+		apnsErr.Reason == "DeviceTokenNotForTopic":
+		return errors.Wrap(service.ErrInvalidToken, apnsErr.Reason)
+	case apnsErr.Reason == "ExpiredProviderToken":
+		p.resetToken()
+	}
+
+	return errors.Wrap(&statusError{code: resp.StatusCode, status: resp.Status, url: u}, apnsErr.Reason)
+}
+
+// token returns cached provider token, token is renewed before APNs considers it expired.
+func (p *APNsProvider) token() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if p.jwt != "" && now.Sub(p.issuedAt) < apnsTokenTTL {
+		return p.jwt, nil
+	}
+
+	jwt, err := signJWT(p.key, p.keyID, map[string]interface{}{
+		"iss": p.teamID,
+		"iat": now.Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	p.jwt = jwt
+	p.issuedAt = now
+
+	return jwt, nil
+}
+
+func (p *APNsProvider) resetToken() {
+	p.mu.Lock()
+	p.jwt = ""
+	p.mu.Unlock()
+}
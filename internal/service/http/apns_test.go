@@ -0,0 +1,218 @@
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/model"
+	"github.com/hummerd/gophercon/internal/service"
+)
+
+// fakeAPNs is a local fake of APNs provider API.
+type fakeAPNs struct {
+	*httptest.Server
+	t   *testing.T
+	key *ecdsa.PrivateKey
+
+	// status and reason replace successful response when status is set
+	status int
+	reason string
+
+	mu       sync.Mutex
+	requests []*apnsRequest
+}
+
+// apnsRequest is a request received by fakeAPNs.
+type apnsRequest struct {
+	token   string
+	header  http.Header
+	payload apnsPayload
+}
+
+func newFakeAPNs(t *testing.T) *fakeAPNs {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fakeAPNs{t: t, key: key}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.push))
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+func (f *fakeAPNs) push(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.URL.Path, "/3/device/") {
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	}
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "bearer ") {
+		f.t.Errorf("unexpected authorization %q", auth)
+	}
+
+	header, claims := verifyJWT(f.t, strings.TrimPrefix(auth, "bearer "), &f.key.PublicKey)
+	if header["kid"] != "KEY123" || claims["iss"] != "TEAM123" {
+		f.t.Errorf("unexpected provider token %v %v", header, claims)
+	}
+
+	req := &apnsRequest{
+		token:  strings.TrimPrefix(r.URL.Path, "/3/device/"),
+		header: r.Header,
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req.payload); err != nil {
+		f.t.Errorf("decoding apns payload: %v", err)
+	}
+	f.requests = append(f.requests, req)
+
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		json.NewEncoder(w).Encode(map[string]string{"reason": f.reason})
+		return
+	}
+
+	w.Header().Set("apns-id", "1")
+}
+
+// provider creates APNsProvider with signing key of fake
+func (f *fakeAPNs) provider() *APNsProvider {
+	f.t.Helper()
+
+	p, err := NewAPNsProvider(f.URL, writeKey(f.t, f.key), "KEY123", "TEAM123", "com.example.app")
+	if err != nil {
+		f.t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestAPNsProviderPush(t *testing.T) {
+	f := newFakeAPNs(t)
+	p := f.provider()
+
+	till := time.Unix(1900000000, 0)
+
+	for _, n := range []*model.Notification{
+		{ID: 5, Type: "news", Title: "Title", Body: "Body", Priority: model.PriorityNormal},
+		{ID: 6, Type: "alert", Title: "Alert", Body: "Now", Priority: model.PriorityHigh, TillTime: &till},
+	} {
+		err := p.Push(context.Background(), "abc123", n)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(f.requests) != 2 {
+		t.Fatalf("expected 2 pushes, got %d", len(f.requests))
+	}
+
+	first, second := f.requests[0], f.requests[1]
+
+	if first.token != "abc123" {
+		t.Fatalf("unexpected device token %q", first.token)
+	}
+
+	if first.header.Get("Authorization") != second.header.Get("Authorization") {
+		t.Fatal("expected provider token to be reused")
+	}
+
+	for name, expected := range map[string]string{
+		"Content-Type":    "application/json",
+		"apns-topic":      "com.example.app",
+		"apns-push-type":  "alert",
+		"apns-priority":   "5",
+		"apns-expiration": "",
+	} {
+		if v := first.header.Get(name); v != expected {
+			t.Fatalf("expected %s header %q, got %q", name, expected, v)
+		}
+	}
+
+	if v := second.header.Get("apns-priority"); v != "10" {
+		t.Fatalf("expected high priority, got %q", v)
+	}
+
+	if v := second.header.Get("apns-expiration"); v != "1900000000" {
+		t.Fatalf("unexpected expiration %q", v)
+	}
+
+	payload := first.payload
+	if payload.APS.Alert.Title != "Title" || payload.APS.Alert.Body != "Body" || payload.APS.Sound != "default" ||
+		payload.NotificationID != 5 || payload.Type != "news" {
+		t.Fatalf("unexpected payload %+v", payload)
+	}
+}
+
+func TestAPNsProviderPushRejected(t *testing.T) {
+	tests := []struct {
+		status  int
+		reason  string
+		invalid bool
+	}{
+		{http.StatusGone, "Unregistered", true},
+		{http.StatusBadRequest, "BadDeviceToken", true},
+		{http.StatusBadRequest, "DeviceTokenNotForTopic", true},
+		{http.StatusBadRequest, "PayloadTooLarge", false},
+		{http.StatusTooManyRequests, "TooManyRequests", false},
+		{http.StatusServiceUnavailable, "ServiceUnavailable", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reason, func(t *testing.T) {
+			f := newFakeAPNs(t)
+			f.status = tt.status
+			f.reason = tt.reason
+
+			err := f.provider().Push(context.Background(), "abc123", &model.Notification{ID: 1, Priority: model.PriorityLow})
+			if err == nil {
+				t.Fatal("expected error")
+			}
+
+			if invalid := errors.Cause(err) == service.ErrInvalidToken; invalid != tt.invalid {
+				t.Fatalf("expected invalid token %v, got %v", tt.invalid, err)
+			}
+		})
+	}
+}
+
+func TestAPNsProviderPushExpiredProviderToken(t *testing.T) {
+	f := newFakeAPNs(t)
+	f.status = http.StatusForbidden
+	f.reason = "ExpiredProviderToken"
+
+	p := f.provider()
+
+	err := p.Push(context.Background(), "abc123", &model.Notification{ID: 1, Priority: model.PriorityLow})
+	if err == nil || errors.Cause(err) == service.ErrInvalidToken {
+		t.Fatalf("expected temporary error, got %v", err)
+	}
+
+	f.status = 0
+
+	err = p.Push(context.Background(), "abc123", &model.Notification{ID: 1, Priority: model.PriorityLow})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.requests[0].header.Get("Authorization") == f.requests[1].header.Get("Authorization") {
+		t.Fatal("expected expired provider token to be renewed")
+	}
+}
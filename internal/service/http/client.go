@@ -98,7 +98,7 @@ func (c *httpClient) DoJSON(ctx context.Context, req *http.Request, result inter
 	defer drainReader(resp.Body, lg)
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("wrong status: %s when calling %s", resp.Status, req.URL)
+		return &statusError{code: resp.StatusCode, status: resp.Status, url: req.URL.String()}
 	}
 
 	r, err := iou.NewPrefixReader(resp.Body, 1024)
@@ -118,6 +118,17 @@ func (c *httpClient) DoJSON(ctx context.Context, req *http.Request, result inter
 	return errors.Wrap(err, "failed to do request: ")
 }
 
+// statusError is returned by DoJSON when response has unexpected status code.
+type statusError struct {
+	code   int
+	status string
+	url    string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("wrong status: %s when calling %s", e.status, e.url)
+}
+
 const defaultResponseLimit = 5 << (10 * 2) // 5MB
 
 func newCustomClient(opts ...httpOpt) *httpClient {
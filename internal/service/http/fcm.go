@@ -0,0 +1,237 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/hummerd/gophercon/internal/model"
+	"github.com/hummerd/gophercon/internal/service"
+)
+
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// NewFCMProvider creates Firebase Cloud Messaging provider authenticated by service account.
+// Token URL from credentials is replaced by tokenURL when it is set.
+func NewFCMProvider(baseURL, credentialsPath, tokenURL string) (*FCMProvider, error) {
+	data, err := ioutil.ReadFile(credentialsPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading fcm credentials %s", credentialsPath)
+	}
+
+	var creds struct {
+		ProjectID   string `json:"project_id"`
+		PrivateKey  string `json:"private_key"`
+		ClientEmail string `json:"client_email"`
+		TokenURI    string `json:"token_uri"`
+	}
+
+	err = json.Unmarshal(data, &creds)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding fcm credentials")
+	}
+
+	key, err := parsePrivateKey([]byte(creds.PrivateKey))
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing fcm credentials")
+	}
+
+	if tokenURL == "" {
+		tokenURL = creds.TokenURI
+	}
+
+	return &FCMProvider{
+		client:    newCustomClient(withServicename("fcm")),
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		projectID: creds.ProjectID,
+		email:     creds.ClientEmail,
+		key:       key,
+		tokenURL:  tokenURL,
+	}, nil
+}
+
+// FCMProvider implements service.PushProvider interface over FCM HTTP v1 API.
+type FCMProvider struct {
+	client    *httpClient
+	baseURL   string
+	projectID string
+	email     string
+	key       crypto.Signer
+	tokenURL  string
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+type fcmRequest struct {
+	Message fcmMessage `json:"message"`
+}
+
+type fcmMessage struct {
+	Token        string            `json:"token"`
+	Notification fcmNotification   `json:"notification"`
+	Data         map[string]string `json:"data,omitempty"`
+	Android      fcmAndroid        `json:"android"`
+}
+
+type fcmNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type fcmAndroid struct {
+	Priority string `json:"priority"`
+}
+
+type fcmErrorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Details []struct {
+			ErrorCode string `json:"errorCode"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+// Platform implements service.PushProvider.
+func (p *FCMProvider) Platform() string {
+	return model.PlatformFCM
+}
+
+// Push sends notification to device token, unregistered token is reported as service.ErrInvalidToken.
+func (p *FCMProvider) Push(ctx context.Context, token string, notification *model.Notification) error {
+	accessToken, err := p.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	priority := "NORMAL"
+	if notification.Priority == model.PriorityHigh || notification.Priority == model.PriorityCritical {
+		priority = "HIGH"
+	}
+
+	body, err := json.Marshal(&fcmRequest{
+		Message: fcmMessage{
+			Token: token,
+			Notification: fcmNotification{
+				Title: notification.Title,
+				Body:  notification.Body,
+			},
+			Data: map[string]string{
+				"notification_id": strconv.Itoa(notification.ID),
+				"type":            notification.Type,
+			},
+			Android: fcmAndroid{Priority: priority},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "encoding fcm message")
+	}
+
+	u := p.baseURL + "/v1/projects/" + url.PathEscape(p.projectID) + "/messages:send"
+
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "creating request")
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := p.client.Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer drainReader(resp.Body, zerolog.Ctx(ctx))
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var fcmErr fcmErrorResponse
+	_ = json.NewDecoder(resp.Body).Decode(&fcmErr)
+
+	if resp.StatusCode == http.StatusNotFound || fcmErr.Error.Status == "NOT_FOUND" {
+		return errors.Wrap(service.ErrInvalidToken, fcmErr.Error.Message)
+	}
+
+	for _, d := range fcmErr.Error.Details {
+		if d.ErrorCode == "UNREGISTERED" {
+			return errors.Wrap(service.ErrInvalidToken, fcmErr.Error.Message)
+		}
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		p.resetToken()
+	}
+
+	return &statusError{code: resp.StatusCode, status: resp.Status, url: u}
+}
+
+// token returns cached OAuth2 access token, new token is requested a minute before expiration.
+func (p *FCMProvider) token(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if p.accessToken != "" && now.Add(time.Minute).Before(p.expiresAt) {
+		return p.accessToken, nil
+	}
+
+	assertion, err := signJWT(p.key, "", map[string]interface{}{
+		"iss":   p.email,
+		"scope": fcmScope,
+		"aud":   p.tokenURL,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", errors.Wrap(err, "creating request")
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+
+	err = p.client.DoJSON(ctx, req, &result)
+	if err != nil {
+		return "", errors.Wrap(err, "requesting fcm access token")
+	}
+
+	p.accessToken = result.AccessToken
+	p.expiresAt = now.Add(time.Duration(result.ExpiresIn) * time.Second)
+
+	return p.accessToken, nil
+}
+
+func (p *FCMProvider) resetToken() {
+	p.mu.Lock()
+	p.accessToken = ""
+	p.mu.Unlock()
+}
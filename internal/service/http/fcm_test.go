@@ -0,0 +1,255 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/model"
+	"github.com/hummerd/gophercon/internal/service"
+)
+
+// fakeFCM is a local fake of OAuth2 token endpoint and FCM HTTP v1 API.
+type fakeFCM struct {
+	*httptest.Server
+	t   *testing.T
+	key *rsa.PrivateKey
+
+	// status and body replace successful send response when status is set
+	status int
+	body   string
+
+	mu       sync.Mutex
+	tokens   int
+	requests []*fcmRequest
+}
+
+func newFakeFCM(t *testing.T) *fakeFCM {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fakeFCM{t: t, key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", f.token)
+	mux.HandleFunc("/v1/projects/my-project/messages:send", f.send)
+
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+func (f *fakeFCM) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		f.t.Errorf("parsing token request: %v", err)
+	}
+
+	if gt := r.PostForm.Get("grant_type"); gt != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+		f.t.Errorf("unexpected grant type %q", gt)
+	}
+
+	_, claims := verifyJWT(f.t, r.PostForm.Get("assertion"), &f.key.PublicKey)
+	if claims["iss"] != "push@my-project.iam.gserviceaccount.com" ||
+		claims["scope"] != fcmScope ||
+		claims["aud"] != f.URL+"/token" {
+		f.t.Errorf("unexpected assertion claims %v", claims)
+	}
+
+	f.mu.Lock()
+	f.tokens++
+	n := f.tokens
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "access-" + strconv.Itoa(n),
+		"expires_in":   3600,
+	})
+}
+
+func (f *fakeFCM) send(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if auth := r.Header.Get("Authorization"); auth != "Bearer access-"+strconv.Itoa(f.tokens) {
+		f.t.Errorf("unexpected authorization %q", auth)
+	}
+
+	var req fcmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		f.t.Errorf("decoding fcm request: %v", err)
+	}
+	f.requests = append(f.requests, &req)
+
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		w.Write([]byte(f.body))
+		return
+	}
+
+	w.Write([]byte(`{"name":"projects/my-project/messages/1"}`))
+}
+
+// provider creates FCMProvider with service account credentials of fake
+func (f *fakeFCM) provider() *FCMProvider {
+	f.t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(f.key)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+
+	creds, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"project_id":   "my-project",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email": "push@my-project.iam.gserviceaccount.com",
+		"token_uri":    "https://oauth2.googleapis.com/token",
+	})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+
+	path := filepath.Join(f.t.TempDir(), "credentials.json")
+	if err := ioutil.WriteFile(path, creds, 0600); err != nil {
+		f.t.Fatal(err)
+	}
+
+	p, err := NewFCMProvider(f.URL+"/", path, f.URL+"/token")
+	if err != nil {
+		f.t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestFCMProviderPush(t *testing.T) {
+	f := newFakeFCM(t)
+	p := f.provider()
+
+	for _, n := range []*model.Notification{
+		{ID: 5, Type: "news", Title: "Title", Body: "Body", Priority: model.PriorityNormal},
+		{ID: 6, Type: "alert", Title: "Alert", Body: "Now", Priority: model.PriorityCritical},
+	} {
+		err := p.Push(context.Background(), "device-token", n)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if f.tokens != 1 {
+		t.Fatalf("expected access token to be reused, got %d token requests", f.tokens)
+	}
+
+	if len(f.requests) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(f.requests))
+	}
+
+	m := f.requests[0].Message
+	if m.Token != "device-token" || m.Notification.Title != "Title" || m.Notification.Body != "Body" {
+		t.Fatalf("unexpected message %+v", m)
+	}
+
+	if m.Data["notification_id"] != "5" || m.Data["type"] != "news" {
+		t.Fatalf("unexpected message data %v", m.Data)
+	}
+
+	if m.Android.Priority != "NORMAL" || f.requests[1].Message.Android.Priority != "HIGH" {
+		t.Fatalf("unexpected android priorities %q, %q", m.Android.Priority, f.requests[1].Message.Android.Priority)
+	}
+}
+
+func TestFCMProviderPushRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		invalid bool
+	}{
+		{
+			name:    "unregistered",
+			status:  http.StatusNotFound,
+			body:    `{"error":{"code":404,"status":"NOT_FOUND","message":"Requested entity was not found.","details":[{"@type":"type.googleapis.com/google.firebase.fcm.v1.FcmError","errorCode":"UNREGISTERED"}]}}`,
+			invalid: true,
+		},
+		{
+			name:    "unregistered detail",
+			status:  http.StatusBadRequest,
+			body:    `{"error":{"code":400,"status":"INVALID_ARGUMENT","details":[{"errorCode":"UNREGISTERED"}]}}`,
+			invalid: true,
+		},
+		{
+			name:   "invalid argument",
+			status: http.StatusBadRequest,
+			body:   `{"error":{"code":400,"status":"INVALID_ARGUMENT","details":[{"errorCode":"INVALID_ARGUMENT"}]}}`,
+		},
+		{
+			name:   "unavailable",
+			status: http.StatusServiceUnavailable,
+			body:   `{"error":{"code":503,"status":"UNAVAILABLE"}}`,
+		},
+		{
+			name:   "not json",
+			status: http.StatusBadGateway,
+			body:   `bad gateway`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeFCM(t)
+			f.status = tt.status
+			f.body = tt.body
+
+			err := f.provider().Push(context.Background(), "device-token", &model.Notification{ID: 1, Priority: model.PriorityLow})
+			if err == nil {
+				t.Fatal("expected error")
+			}
+
+			if invalid := errors.Cause(err) == service.ErrInvalidToken; invalid != tt.invalid {
+				t.Fatalf("expected invalid token %v, got %v", tt.invalid, err)
+			}
+		})
+	}
+}
+
+func TestFCMProviderPushUnauthorized(t *testing.T) {
+	f := newFakeFCM(t)
+	f.status = http.StatusUnauthorized
+	f.body = `{"error":{"code":401,"status":"UNAUTHENTICATED"}}`
+
+	p := f.provider()
+
+	err := p.Push(context.Background(), "device-token", &model.Notification{ID: 1, Priority: model.PriorityLow})
+	if err == nil || errors.Cause(err) == service.ErrInvalidToken {
+		t.Fatalf("expected temporary error, got %v", err)
+	}
+
+	f.status = 0
+
+	err = p.Push(context.Background(), "device-token", &model.Notification{ID: 1, Priority: model.PriorityLow})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.tokens != 2 {
+		t.Fatalf("expected rejected access token to be renewed, got %d token requests", f.tokens)
+	}
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// signJWT returns compact JWT of claims signed with RS256 or ES256 depending on key type.
+func signJWT(key crypto.Signer, keyID string, claims interface{}) (string, error) {
+	header := map[string]string{"typ": "JWT"}
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		header["alg"] = "RS256"
+	case *ecdsa.PrivateKey:
+		header["alg"] = "ES256"
+	default:
+		return "", errors.Errorf("unsupported jwt key %T", key)
+	}
+
+	if keyID != "" {
+		header["kid"] = keyID
+	}
+
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", errors.Wrap(err, "encoding jwt header")
+	}
+
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", errors.Wrap(err, "encoding jwt claims")
+	}
+
+	input := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	digest := sha256.Sum256([]byte(input))
+
+	var sig []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		// ES256 signature is r and s padded to curve size, not ASN.1
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k, digest[:])
+		if err == nil {
+			size := (k.Curve.Params().BitSize + 7) / 8
+			sig = make([]byte, 2*size)
+			r.FillBytes(sig[:size])
+			s.FillBytes(sig[size:])
+		}
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "signing jwt")
+	}
+
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// parsePrivateKey parses PEM encoded PKCS#8 private key.
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found in private key")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing private key")
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.Errorf("unsupported private key %T", key)
+	}
+
+	return signer, nil
+}
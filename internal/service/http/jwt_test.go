@@ -0,0 +1,114 @@
+package http
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeKey writes key as PEM encoded PKCS#8 to temporary file and returns its path.
+func writeKey(t *testing.T, key crypto.Signer) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+
+	err = ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// verifyJWT verifies signature of compact JWT with public key and decodes its header and claims.
+func verifyJWT(t *testing.T, token string, pub crypto.PublicKey) (map[string]string, map[string]interface{}) {
+	t.Helper()
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("malformed jwt %q", token)
+	}
+
+	var (
+		header map[string]string
+		claims map[string]interface{}
+	)
+
+	decode := func(s string, v interface{}) []byte {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			t.Fatalf("decoding jwt part %q: %v", s, err)
+		}
+
+		if v != nil {
+			if err := json.Unmarshal(b, v); err != nil {
+				t.Fatalf("decoding jwt part %s: %v", b, err)
+			}
+		}
+
+		return b
+	}
+
+	decode(parts[0], &header)
+	decode(parts[1], &claims)
+	sig := decode(parts[2], nil)
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		if header["alg"] != "RS256" {
+			t.Fatalf("unexpected jwt algorithm %q", header["alg"])
+		}
+
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig); err != nil {
+			t.Fatalf("invalid jwt signature: %v", err)
+		}
+	case *ecdsa.PublicKey:
+		if header["alg"] != "ES256" {
+			t.Fatalf("unexpected jwt algorithm %q", header["alg"])
+		}
+
+		if len(sig) != 64 {
+			t.Fatalf("unexpected ES256 signature length %d", len(sig))
+		}
+
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(k, digest[:], r, s) {
+			t.Fatal("invalid jwt signature")
+		}
+	default:
+		t.Fatalf("unsupported public key %T", pub)
+	}
+
+	return header, claims
+}
+
+func TestSignJWTUnsupportedKey(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = signJWT(key, "", map[string]string{})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
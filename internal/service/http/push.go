@@ -0,0 +1,29 @@
+package http
+
+import (
+	"github.com/hummerd/gophercon/internal/config"
+	"github.com/hummerd/gophercon/internal/service"
+)
+
+// NewPushProviders creates push providers of platforms that are configured.
+func NewPushProviders(cfg *config.Config) (service.PushProviders, error) {
+	providers := service.PushProviders{}
+
+	if cfg.FCMCredentials != "" {
+		fcm, err := NewFCMProvider(cfg.FCMBaseURL, cfg.FCMCredentials, cfg.FCMTokenURL)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, fcm)
+	}
+
+	if cfg.APNsKey != "" {
+		apns, err := NewAPNsProvider(cfg.APNsBaseURL, cfg.APNsKey, cfg.APNsKeyID, cfg.APNsTeamID, cfg.APNsTopic)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, apns)
+	}
+
+	return providers, nil
+}
@@ -4,15 +4,17 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"net/url"
 
 	"github.com/pkg/errors"
 
 	"github.com/hummerd/gophercon/internal/model"
+	"github.com/hummerd/gophercon/internal/service"
 )
 
 var (
 	// ErrSessionStoreUnavailble error is returned by package in case request can't be performed dut to unavailability.
-	ErrSessionStoreUnavailble = errors.New("can't connect to session-store service")
+	ErrSessionStoreUnavailble = service.ErrSessionStoreUnavailble
 )
 
 // NewSessionStore creates new instance of the session store.
@@ -47,5 +49,23 @@ func (s *SessionStore) GetSessionByToken(ctx context.Context, token string) (*mo
 
 	var result sessionByTokenResponse
 	err = s.client.DoJSON(ctx, req, &result)
-	return result.Session, err
+	if err == nil {
+		return result.Session, nil
+	}
+
+	switch cause := errors.Cause(err).(type) {
+	case *url.Error:
+		return nil, errors.Wrap(ErrSessionStoreUnavailble, cause.Error())
+	case *statusError:
+		switch {
+		case cause.code == http.StatusUnauthorized ||
+			cause.code == http.StatusForbidden ||
+			cause.code == http.StatusNotFound:
+			return nil, nil
+		case cause.code >= http.StatusInternalServerError:
+			return nil, errors.Wrap(ErrSessionStoreUnavailble, cause.Error())
+		}
+	}
+
+	return nil, err
 }
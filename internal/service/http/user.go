@@ -0,0 +1,62 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+// NewUserStore creates new instance of the user store.
+func NewUserStore() *UserStore {
+	return &UserStore{
+		client:   newCustomClient(withServicename("users")),
+		usersAPI: "example.com/api/v1/users/",
+	}
+}
+
+// UserStore implements service.UserStore interface.
+type UserStore struct {
+	client *httpClient
+
+	usersAPI string
+}
+
+type userResponse struct {
+	User *struct {
+		model.User
+		Locale string `json:"locale"`
+	} `json:"user"`
+}
+
+// GetUser gets user's profile from users service.
+func (s *UserStore) GetUser(ctx context.Context, id int64) (*model.User, error) {
+	req, err := http.NewRequest(http.MethodGet, s.usersAPI+strconv.FormatInt(id, 10), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating request")
+	}
+	req = req.WithContext(ctx)
+
+	var result userResponse
+	err = s.client.DoJSON(ctx, req, &result)
+	if cause, ok := errors.Cause(err).(*statusError); ok && cause.code == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting user %d", id)
+	}
+
+	if result.User == nil {
+		return nil, nil
+	}
+
+	user := result.User.User
+	if result.User.Locale != "" {
+		user.Locales = []string{result.User.Locale}
+	}
+
+	return &user, nil
+}
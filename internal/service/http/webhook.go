@@ -0,0 +1,123 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+const (
+	// HeaderWebhookSignature holds signature of delivery, see Sign
+	HeaderWebhookSignature = "X-Webhook-Signature"
+	// HeaderWebhookEvent holds event type of delivery
+	HeaderWebhookEvent = "X-Webhook-Event"
+	// HeaderWebhookDelivery holds delivery id, it is the same for all attempts of delivery
+	HeaderWebhookDelivery = "X-Webhook-Delivery"
+)
+
+var (
+	// ErrInvalidSignature is returned by VerifySignature when signature does not match payload.
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrExpiredSignature is returned by VerifySignature when signature is too old.
+	ErrExpiredSignature = errors.New("expired webhook signature")
+)
+
+// NewWebhookSender creates new instance of the webhook sender.
+func NewWebhookSender() *WebhookSender {
+	return &WebhookSender{
+		client: newCustomClient(withServicename("webhooks")),
+	}
+}
+
+// WebhookSender implements service.WebhookSender interface.
+type WebhookSender struct {
+	client *httpClient
+}
+
+// Send posts delivery's payload to webhook's url, any 2xx response means delivery is accepted.
+func (s *WebhookSender) Send(ctx context.Context, webhook *model.Webhook, delivery *model.WebhookDelivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, errors.Wrap(err, "creating request")
+	}
+	req = req.WithContext(ctx)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderWebhookEvent, delivery.Event)
+	req.Header.Set(HeaderWebhookDelivery, strconv.Itoa(delivery.ID))
+	req.Header.Set(HeaderWebhookSignature, Sign(webhook.Secret, time.Now().Unix(), delivery.Payload))
+
+	resp, err := s.client.Do(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	defer drainReader(resp.Body, zerolog.Ctx(ctx))
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return resp.StatusCode, &statusError{code: resp.StatusCode, status: resp.Status, url: webhook.URL}
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Sign returns signature of payload sent at unix timestamp in form "t=<timestamp>,v1=<signature>",
+// where signature is hex encoded HMAC-SHA256 of "<timestamp>.<payload>" keyed with webhook's secret.
+func Sign(secret string, timestamp int64, payload []byte) string {
+	ts := strconv.FormatInt(timestamp, 10)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac(secret, ts, payload))
+}
+
+// VerifySignature checks signature header of payload received at now,
+// signatures made more than tolerance ago are rejected to prevent replays.
+func VerifySignature(secret, header string, payload []byte, now time.Time, tolerance time.Duration) error {
+	var ts, sig string
+
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		switch kv[0] {
+		case "t":
+			ts = kv[1]
+		case "v1":
+			sig = kv[1]
+		}
+	}
+
+	timestamp, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	expected, err := hex.DecodeString(sig)
+	if err != nil || !hmac.Equal(expected, mac(secret, ts, payload)) {
+		return ErrInvalidSignature
+	}
+
+	if now.Sub(time.Unix(timestamp, 0)) > tolerance {
+		return ErrExpiredSignature
+	}
+
+	return nil
+}
+
+func mac(secret, timestamp string, payload []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(payload)
+	return h.Sum(nil)
+}
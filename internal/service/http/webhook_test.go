@@ -0,0 +1,152 @@
+package http
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+func TestWebhookSenderSend(t *testing.T) {
+	const secret = "secret"
+	payload := []byte(`{"event":"notification.published"}`)
+
+	var (
+		req  *http.Request
+		body []byte
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = r
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	code, err := NewWebhookSender().Send(
+		context.Background(),
+		&model.Webhook{URL: srv.URL + "/hook", Secret: secret},
+		&model.WebhookDelivery{ID: 42, Event: model.EventNotificationPublished, Payload: payload},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, code)
+	}
+
+	if req.Method != http.MethodPost || req.URL.Path != "/hook" {
+		t.Fatalf("unexpected request %s %s", req.Method, req.URL.Path)
+	}
+
+	if ct := req.Header.Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	if e := req.Header.Get(HeaderWebhookEvent); e != model.EventNotificationPublished {
+		t.Fatalf("unexpected event header %q", e)
+	}
+
+	if id := req.Header.Get(HeaderWebhookDelivery); id != "42" {
+		t.Fatalf("unexpected delivery header %q", id)
+	}
+
+	if string(body) != string(payload) {
+		t.Fatalf("unexpected body %s", body)
+	}
+
+	err = VerifySignature(secret, req.Header.Get(HeaderWebhookSignature), body, time.Now(), time.Minute)
+	if err != nil {
+		t.Fatalf("signature is not verified: %v", err)
+	}
+}
+
+func TestWebhookSenderSendRejected(t *testing.T) {
+	tests := []int{
+		http.StatusBadRequest,
+		http.StatusGone,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+	}
+
+	for _, status := range tests {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(status)
+			}))
+			defer srv.Close()
+
+			code, err := NewWebhookSender().Send(
+				context.Background(),
+				&model.Webhook{URL: srv.URL},
+				&model.WebhookDelivery{ID: 1, Payload: []byte("{}")},
+			)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+
+			if code != status {
+				t.Fatalf("expected status %d, got %d", status, code)
+			}
+		})
+	}
+}
+
+func TestWebhookSenderSendUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	code, err := NewWebhookSender().Send(
+		context.Background(),
+		&model.Webhook{URL: srv.URL},
+		&model.WebhookDelivery{ID: 1, Payload: []byte("{}")},
+	)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	if code != 0 {
+		t.Fatalf("expected zero status of unreachable endpoint, got %d", code)
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	const secret = "secret"
+	payload := []byte(`{"id":1}`)
+	signedAt := time.Unix(1700000000, 0)
+	header := Sign(secret, signedAt.Unix(), payload)
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		payload []byte
+		now     time.Time
+		err     error
+	}{
+		{"valid", secret, header, payload, signedAt.Add(time.Minute), nil},
+		{"reordered", secret, header[len("t=1700000000,"):] + ",t=1700000000", payload, signedAt, nil},
+		{"wrong secret", "other", header, payload, signedAt, ErrInvalidSignature},
+		{"tampered payload", secret, header, []byte(`{"id":2}`), signedAt, ErrInvalidSignature},
+		{"tampered timestamp", secret, "t=1700000001" + header[len("t=1700000000"):], payload, signedAt, ErrInvalidSignature},
+		{"missing timestamp", secret, header[len("t=1700000000,"):], payload, signedAt, ErrInvalidSignature},
+		{"missing signature", secret, "t=1700000000", payload, signedAt, ErrInvalidSignature},
+		{"malformed signature", secret, "t=1700000000,v1=zz", payload, signedAt, ErrInvalidSignature},
+		{"empty", secret, "", payload, signedAt, ErrInvalidSignature},
+		{"expired", secret, header, payload, signedAt.Add(6 * time.Minute), ErrExpiredSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySignature(tt.secret, tt.header, tt.payload, tt.now, 5*time.Minute)
+			if err != tt.err {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+var (
+	// ErrInvalidToken error is returned by push provider when device token is unregistered or malformed,
+	// such tokens must not be used anymore.
+	ErrInvalidToken = errors.New("invalid device token")
+)
+
+// PushProvider interface provides method to send push notification to device of provider's platform.
+type PushProvider interface {
+	Platform() string
+	Push(ctx context.Context, token string, notification *model.Notification) error
+}
+
+// PushProviders is a set of configured push providers.
+type PushProviders []PushProvider
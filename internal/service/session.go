@@ -3,10 +3,18 @@ package service
 import (
 	"context"
 
+	"github.com/pkg/errors"
+
 	"github.com/hummerd/gophercon/internal/model"
 )
 
+var (
+	// ErrSessionStoreUnavailble error is returned in case session can't be resolved due to session-store unavailability.
+	ErrSessionStoreUnavailble = errors.New("can't connect to session-store service")
+)
+
 // SessionStore interface provides method to interacts with session-store.
+// GetSessionByToken returns nil session without error when token is unknown.
 type SessionStore interface {
 	GetSessionByToken(ctx context.Context, token string) (*model.Session, error)
 }
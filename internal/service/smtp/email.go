@@ -0,0 +1,298 @@
+// Package smtp implements email delivery channel over SMTP.
+package smtp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	htmltemplate "html/template"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/config"
+	"github.com/hummerd/gophercon/internal/model"
+	"github.com/hummerd/gophercon/internal/service"
+)
+
+// TLS modes.
+const (
+	// TLSNone sends email without encryption, it is intended for local servers.
+	TLSNone = "none"
+	// TLSStartTLS upgrades connection with STARTTLS, server without STARTTLS support is an error.
+	TLSStartTLS = "starttls"
+)
+
+const (
+	defaultTextTemplate = "{{.Notification.Title}}\r\n\r\n{{.Notification.Body}}\r\n"
+	defaultHTMLTemplate = `<!DOCTYPE html>
+<html>
+<body>
+<h1>{{.Notification.Title}}</h1>
+<p>{{.Notification.Body}}</p>
+</body>
+</html>
+`
+)
+
+// NewEmailChannel creates email channel, templates of email parts are parsed from configured files.
+func NewEmailChannel(cfg *config.Config) (*EmailChannel, error) {
+	host, _, err := net.SplitHostPort(cfg.SMTPAddr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing smtp address %q", cfg.SMTPAddr)
+	}
+
+	if cfg.SMTPTLS != TLSNone && cfg.SMTPTLS != TLSStartTLS {
+		return nil, errors.Errorf("unknown smtp tls mode %q", cfg.SMTPTLS)
+	}
+
+	from, err := mail.ParseAddress(cfg.SMTPFrom)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing sender address %q", cfg.SMTPFrom)
+	}
+
+	textSrc, err := readTemplate(cfg.EmailTextTemplate, defaultTextTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	htmlSrc, err := readTemplate(cfg.EmailHTMLTemplate, defaultHTMLTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := texttemplate.New("text").Option("missingkey=error").Parse(textSrc)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing email text template")
+	}
+
+	html, err := htmltemplate.New("html").Option("missingkey=error").Parse(htmlSrc)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing email html template")
+	}
+
+	return &EmailChannel{
+		addr:      cfg.SMTPAddr,
+		host:      host,
+		tls:       cfg.SMTPTLS,
+		tlsConfig: &tls.Config{ServerName: host},
+		username:  cfg.SMTPUsername,
+		password:  cfg.SMTPPassword,
+		from:      from,
+		timeout:   cfg.SMTPTimeout,
+		text:      text,
+		html:      html,
+	}, nil
+}
+
+// EmailChannel implements service.Channel interface, every email is sent over new connection.
+type EmailChannel struct {
+	addr      string
+	host      string
+	tls       string
+	tlsConfig *tls.Config
+	username  string
+	password  string
+	from      *mail.Address
+	timeout   time.Duration
+
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// emailData is a data of email templates
+type emailData struct {
+	User         *model.User
+	Notification *model.Notification
+}
+
+// Name implements service.Channel.
+func (c *EmailChannel) Name() string {
+	return model.ChannelEmail
+}
+
+// Send sends notification to user's email as multipart message with text and HTML parts.
+// Recipient rejected by server is reported as service.ErrUndeliverable.
+func (c *EmailChannel) Send(ctx context.Context, user *model.User, notification *model.Notification) error {
+	if user.Email == "" {
+		return errors.Wrapf(service.ErrUndeliverable, "user %d has no email", user.ID)
+	}
+
+	msg, err := c.message(user, notification)
+	if err != nil {
+		return err
+	}
+
+	err = c.send(ctx, user.Email, msg)
+	if tpErr, ok := errors.Cause(err).(*textproto.Error); ok && rejected(tpErr.Code) {
+		return errors.Wrap(service.ErrUndeliverable, tpErr.Error())
+	}
+
+	return err
+}
+
+func (c *EmailChannel) send(ctx context.Context, to string, msg []byte) error {
+	d := net.Dialer{Timeout: c.timeout}
+	conn, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return errors.Wrapf(err, "connecting to smtp server %s", c.addr)
+	}
+
+	deadline := time.Now().Add(c.timeout)
+	if dl, ok := ctx.Deadline(); ok && dl.Before(deadline) {
+		deadline = dl
+	}
+
+	err = conn.SetDeadline(deadline)
+	if err != nil {
+		conn.Close()
+		return errors.Wrap(err, "setting smtp connection deadline")
+	}
+
+	client, err := smtp.NewClient(conn, c.host)
+	if err != nil {
+		conn.Close()
+		return errors.Wrap(err, "greeting smtp server")
+	}
+	defer client.Close()
+
+	if c.tls == TLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.Errorf("smtp server %s does not support STARTTLS", c.addr)
+		}
+
+		err = client.StartTLS(c.tlsConfig)
+		if err != nil {
+			return errors.Wrap(err, "starting tls")
+		}
+	}
+
+	if c.username != "" {
+		err = client.Auth(smtp.PlainAuth("", c.username, c.password, c.host))
+		if err != nil {
+			return errors.Wrap(err, "authenticating to smtp server")
+		}
+	}
+
+	err = client.Mail(c.from.Address)
+	if err != nil {
+		return errors.Wrap(err, "setting sender")
+	}
+
+	err = client.Rcpt(to)
+	if err != nil {
+		return errors.Wrapf(err, "setting recipient %s", to)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return errors.Wrap(err, "starting email data")
+	}
+
+	_, err = w.Write(msg)
+	if err != nil {
+		return errors.Wrap(err, "writing email data")
+	}
+
+	err = w.Close()
+	if err != nil {
+		return errors.Wrap(err, "finishing email data")
+	}
+
+	return errors.Wrap(client.Quit(), "quitting smtp session")
+}
+
+// message renders MIME message with text and HTML alternatives
+func (c *EmailChannel) message(user *model.User, notification *model.Notification) ([]byte, error) {
+	data := &emailData{User: user, Notification: notification}
+
+	var text, html bytes.Buffer
+
+	err := c.text.Execute(&text, data)
+	if err != nil {
+		return nil, errors.Wrap(err, "rendering email text")
+	}
+
+	err = c.html.Execute(&html, data)
+	if err != nil {
+		return nil, errors.Wrap(err, "rendering email html")
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=utf-8", text.Bytes()},
+		{"text/html; charset=utf-8", html.Bytes()},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "creating email part")
+		}
+
+		qw := quotedprintable.NewWriter(pw)
+		if _, err := qw.Write(part.content); err != nil {
+			return nil, errors.Wrap(err, "encoding email part")
+		}
+		if err := qw.Close(); err != nil {
+			return nil, errors.Wrap(err, "encoding email part")
+		}
+	}
+
+	err = mw.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "finishing email parts")
+	}
+
+	to := &mail.Address{
+		Name:    strings.TrimSpace(user.FirstName + " " + user.LastName),
+		Address: user.Email,
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", c.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", notification.Title))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <notification-%d-%d@%s>\r\n", notification.ID, user.ID, c.host)
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+func readTemplate(path, def string) (string, error) {
+	if path == "" {
+		return def, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "reading email template %s", path)
+	}
+
+	return string(b), nil
+}
+
+// rejected reports whether smtp reply code means recipient mailbox is unavailable or invalid.
+func rejected(code int) bool {
+	return code == 550 || code == 551 || code == 553
+}
@@ -0,0 +1,237 @@
+package smtp
+
+import (
+	"context"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/hummerd/gophercon/internal/config"
+	"github.com/hummerd/gophercon/internal/model"
+	"github.com/hummerd/gophercon/internal/service"
+)
+
+func newTestChannel(t *testing.T, addr, tlsMode string) *EmailChannel {
+	t.Helper()
+
+	c, err := NewEmailChannel(&config.Config{
+		SMTPAddr:    addr,
+		SMTPTLS:     tlsMode,
+		SMTPFrom:    "Notifications <noreply@example.com>",
+		SMTPTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func testUser() *model.User {
+	return &model.User{ID: 7, Email: "jane@example.com", FirstName: "Jane", LastName: "Doe"}
+}
+
+func testNotification() *model.Notification {
+	return &model.Notification{ID: 3, Type: "news", Title: "Hello <Jane>", Body: "Body & more"}
+}
+
+func TestEmailChannelSend(t *testing.T) {
+	srv := newFakeServer(t)
+	c := newTestChannel(t, srv.addr(), TLSNone)
+
+	err := c.Send(context.Background(), testUser(), testNotification())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := srv.received()
+	if len(messages) != 1 {
+		t.Fatalf("expected single message, got %d", len(messages))
+	}
+
+	m := messages[0]
+	if m.from != "noreply@example.com" || len(m.to) != 1 || m.to[0] != "jane@example.com" {
+		t.Fatalf("unexpected envelope from %s to %v", m.from, m.to)
+	}
+
+	if m.tls || m.auth != "" {
+		t.Fatalf("expected plain unauthenticated session, got tls %v auth %q", m.tls, m.auth)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(m.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if subject != "Hello <Jane>" {
+		t.Fatalf("unexpected subject %q", subject)
+	}
+
+	to, err := msg.Header.AddressList("To")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(to) != 1 || to[0].Name != "Jane Doe" || to[0].Address != "jane@example.com" {
+		t.Fatalf("unexpected recipient %v", to)
+	}
+
+	if id := msg.Header.Get("Message-ID"); id != "<notification-3-7@127.0.0.1>" {
+		t.Fatalf("unexpected message id %q", id)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("unexpected content type %q", mediaType)
+	}
+
+	parts := map[string]string{}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := mr.NextRawPart()
+		if err != nil {
+			break
+		}
+
+		if te := p.Header.Get("Content-Transfer-Encoding"); te != "quoted-printable" {
+			t.Fatalf("unexpected transfer encoding %q", te)
+		}
+
+		b, err := ioutil.ReadAll(quotedprintable.NewReader(p))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		parts[p.Header.Get("Content-Type")] = string(b)
+	}
+
+	if text := parts["text/plain; charset=utf-8"]; text != "Hello <Jane>\n\nBody & more\n" {
+		t.Fatalf("unexpected text part %q", text)
+	}
+
+	html := parts["text/html; charset=utf-8"]
+	if !strings.Contains(html, "<h1>Hello &lt;Jane&gt;</h1>") || !strings.Contains(html, "<p>Body &amp; more</p>") {
+		t.Fatalf("unexpected html part %q", html)
+	}
+}
+
+func TestEmailChannelSendStartTLS(t *testing.T) {
+	srv := newFakeServer(t)
+
+	serverConfig, roots := newTestCertificate(t)
+	srv.tlsConfig = serverConfig
+
+	c := newTestChannel(t, srv.addr(), TLSStartTLS)
+	c.tlsConfig.RootCAs = roots
+	c.username = "user"
+	c.password = "secret"
+
+	err := c.Send(context.Background(), testUser(), testNotification())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := srv.received()
+	if len(messages) != 1 {
+		t.Fatalf("expected single message, got %d", len(messages))
+	}
+
+	if !messages[0].tls {
+		t.Fatal("expected message sent over tls")
+	}
+
+	if messages[0].auth != "\x00user\x00secret" {
+		t.Fatalf("unexpected authentication %q", messages[0].auth)
+	}
+}
+
+func TestEmailChannelSendStartTLSUnsupported(t *testing.T) {
+	srv := newFakeServer(t)
+	c := newTestChannel(t, srv.addr(), TLSStartTLS)
+
+	err := c.Send(context.Background(), testUser(), testNotification())
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	if errors.Cause(err) == service.ErrUndeliverable {
+		t.Fatalf("expected temporary error, got %v", err)
+	}
+
+	if len(srv.received()) != 0 {
+		t.Fatal("expected no message sent without tls")
+	}
+}
+
+func TestEmailChannelSendRejected(t *testing.T) {
+	tests := []struct {
+		reply         string
+		undeliverable bool
+	}{
+		{"550 5.1.1 no such user", true},
+		{"551 user not local", true},
+		{"553 mailbox name not allowed", true},
+		{"450 mailbox busy", false},
+		{"452 insufficient storage", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reply, func(t *testing.T) {
+			srv := newFakeServer(t)
+			srv.rcptReply = tt.reply
+
+			c := newTestChannel(t, srv.addr(), TLSNone)
+
+			err := c.Send(context.Background(), testUser(), testNotification())
+			if err == nil {
+				t.Fatal("expected error")
+			}
+
+			if undeliverable := errors.Cause(err) == service.ErrUndeliverable; undeliverable != tt.undeliverable {
+				t.Fatalf("expected undeliverable %v, got %v", tt.undeliverable, err)
+			}
+		})
+	}
+}
+
+func TestEmailChannelSendNoEmail(t *testing.T) {
+	c := newTestChannel(t, "127.0.0.1:1", TLSNone)
+
+	err := c.Send(context.Background(), &model.User{ID: 1}, testNotification())
+	if errors.Cause(err) != service.ErrUndeliverable {
+		t.Fatalf("expected %v, got %v", service.ErrUndeliverable, err)
+	}
+}
+
+func TestEmailChannelSendUnreachable(t *testing.T) {
+	srv := newFakeServer(t)
+	addr := srv.addr()
+	srv.close()
+
+	c := newTestChannel(t, addr, TLSNone)
+
+	err := c.Send(context.Background(), testUser(), testNotification())
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	if errors.Cause(err) == service.ErrUndeliverable {
+		t.Fatalf("expected temporary error, got %v", err)
+	}
+}
@@ -0,0 +1,248 @@
+package smtp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeMessage is an email received by fakeServer.
+type fakeMessage struct {
+	from string
+	to   []string
+	data string
+	// tls reports whether message was sent over connection upgraded with STARTTLS
+	tls bool
+	// auth is decoded AUTH PLAIN response
+	auth string
+}
+
+// fakeServer is an in-process SMTP server speaking just enough of the protocol
+// to receive messages from net/smtp client.
+type fakeServer struct {
+	ln net.Listener
+	// tlsConfig enables STARTTLS extension when it is set
+	tlsConfig *tls.Config
+	// rcptReply replaces successful reply to RCPT command when it is set, e.g. "550 no such user"
+	rcptReply string
+
+	mu       sync.Mutex
+	messages []*fakeMessage
+	wg       sync.WaitGroup
+}
+
+// newFakeServer starts fakeServer on random local port, it is closed on test cleanup.
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &fakeServer{ln: ln}
+	t.Cleanup(s.close)
+
+	s.wg.Add(1)
+	go s.serve()
+
+	return s
+}
+
+func (s *fakeServer) addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *fakeServer) received() []*fakeMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*fakeMessage{}, s.messages...)
+}
+
+func (s *fakeServer) close() {
+	s.ln.Close()
+	s.wg.Wait()
+}
+
+func (s *fakeServer) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+
+			conn.SetDeadline(time.Now().Add(5 * time.Second))
+			s.session(conn)
+		}()
+	}
+}
+
+func (s *fakeServer) session(conn net.Conn) {
+	tp := textproto.NewConn(conn)
+	msg := &fakeMessage{}
+
+	tp.PrintfLine("220 localhost fake ESMTP")
+
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		cmd, arg := line, ""
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			cmd, arg = line[:i], line[i+1:]
+		}
+
+		switch strings.ToUpper(cmd) {
+		case "EHLO", "HELO":
+			ext := []string{"localhost", "8BITMIME", "AUTH PLAIN"}
+			if s.tlsConfig != nil && !msg.tls {
+				ext = append(ext, "STARTTLS")
+			}
+
+			for i, e := range ext {
+				sep := "-"
+				if i == len(ext)-1 {
+					sep = " "
+				}
+				tp.PrintfLine("250%s%s", sep, e)
+			}
+		case "STARTTLS":
+			if s.tlsConfig == nil || msg.tls {
+				tp.PrintfLine("502 not supported")
+				continue
+			}
+
+			tp.PrintfLine("220 ready to start tls")
+
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+
+			conn = tlsConn
+			tp = textproto.NewConn(conn)
+			// Client starts over after upgrade
+			msg = &fakeMessage{tls: true}
+		case "AUTH":
+			fields := strings.Fields(arg)
+			if len(fields) != 2 || fields[0] != "PLAIN" {
+				tp.PrintfLine("504 unsupported authentication")
+				continue
+			}
+
+			b, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				tp.PrintfLine("501 malformed authentication")
+				continue
+			}
+
+			msg.auth = string(b)
+			tp.PrintfLine("235 authenticated")
+		case "MAIL":
+			msg.from = address(arg)
+			tp.PrintfLine("250 sender ok")
+		case "RCPT":
+			if s.rcptReply != "" {
+				tp.PrintfLine("%s", s.rcptReply)
+				continue
+			}
+
+			msg.to = append(msg.to, address(arg))
+			tp.PrintfLine("250 recipient ok")
+		case "DATA":
+			tp.PrintfLine("354 end data with <CR><LF>.<CR><LF>")
+
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.data = string(data)
+
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+
+			msg = &fakeMessage{tls: msg.tls, auth: msg.auth}
+			tp.PrintfLine("250 queued")
+		case "RSET", "NOOP":
+			tp.PrintfLine("250 ok")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 unknown command")
+		}
+	}
+}
+
+// address extracts address from "FROM:<a@b>" or "TO:<a@b>" argument
+func address(arg string) string {
+	start := strings.IndexByte(arg, '<')
+	end := strings.IndexByte(arg, '>')
+	if start < 0 || end < start {
+		return ""
+	}
+
+	return arg[start+1 : end]
+}
+
+// newTestCertificate creates self-signed certificate of 127.0.0.1
+// and returns server config using it along with pool trusting it.
+func newTestCertificate(t *testing.T) (*tls.Config, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake smtp"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}, pool
+}
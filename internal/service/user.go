@@ -0,0 +1,13 @@
+package service
+
+import (
+	"context"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+// UserStore interface provides method to get user's profile from users service.
+// GetUser returns nil user without error when user is unknown.
+type UserStore interface {
+	GetUser(ctx context.Context, id int64) (*model.User, error)
+}
@@ -0,0 +1,14 @@
+package service
+
+import (
+	"context"
+
+	"github.com/hummerd/gophercon/internal/model"
+)
+
+// WebhookSender interface provides method to deliver signed event payloads to webhook endpoints.
+// Send returns status code of endpoint's response, zero code means endpoint was not reached.
+// Error is returned when delivery was not accepted by endpoint.
+type WebhookSender interface {
+	Send(ctx context.Context, webhook *model.Webhook, delivery *model.WebhookDelivery) (int, error)
+}
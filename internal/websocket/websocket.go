@@ -0,0 +1,452 @@
+// Package websocket implements server side of the WebSocket protocol (RFC 6455)
+// sufficient for exchanging small messages with clients: no extensions and no subprotocols.
+//
+// The only WebSocket library available to dep managed dependencies of the service is
+// gorilla/websocket v1.2.0, which does not treat malformed close frames as protocol errors.
+// Server side subset needed here is small, so it is implemented and tested in place.
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// Message opcodes.
+const (
+	OpContinuation = 0x0
+	OpText         = 0x1
+	OpBinary       = 0x2
+	OpClose        = 0x8
+	OpPing         = 0x9
+	OpPong         = 0xA
+)
+
+// Close codes.
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	CloseNoStatus        = 1005
+	CloseInvalidPayload  = 1007
+	ClosePolicyViolation = 1008
+	CloseTooLarge        = 1009
+	CloseInternalError   = 1011
+	CloseTryAgainLater   = 1013
+)
+
+const (
+	acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	// maxControlPayload is a limit of control frame payload set by protocol
+	maxControlPayload = 125
+	// defaultReadLimit is a default limit of received message size
+	defaultReadLimit = 64 << 10
+)
+
+var (
+	// ErrBadHandshake is returned by Upgrade when request is not a valid WebSocket handshake.
+	ErrBadHandshake = errors.New("bad websocket handshake")
+	// ErrBadOrigin is returned by Upgrade when request comes from a page of not allowed origin.
+	ErrBadOrigin = errors.New("websocket origin is not allowed")
+	// ErrMessageTooLarge is returned when received message exceeds read limit.
+	ErrMessageTooLarge = errors.New("websocket message is too large")
+	// ErrClosed is returned on write to connection after close frame was sent.
+	ErrClosed = errors.New("websocket connection is closed")
+)
+
+// CloseError is returned by ReadMessage when peer closes connection.
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket closed: %d %s", e.Code, e.Text)
+}
+
+// IsUpgrade reports whether request asks to upgrade connection to WebSocket.
+func IsUpgrade(r *http.Request) bool {
+	return hasToken(r.Header.Get("Connection"), "upgrade") &&
+		strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// Upgrade completes WebSocket handshake and takes over request's connection.
+// Browsers send cookies with cross-site WebSocket handshakes, so requests with Origin header
+// are accepted only from the request's host or from allowed origins ("scheme://host[:port]").
+// On failure it responds with an error itself, so caller must not write to w.
+// Deadlines set by http.Server are cleared, connection's owner is responsible for them.
+func Upgrade(w http.ResponseWriter, r *http.Request, allowedOrigins []string) (*Conn, error) {
+	if r.Method != http.MethodGet || !IsUpgrade(r) {
+		http.Error(w, "websocket upgrade required", http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+
+	if !originAllowed(r, allowedOrigins) {
+		http.Error(w, "websocket origin is not allowed", http.StatusForbidden)
+		return nil, ErrBadOrigin
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return nil, ErrBadHandshake
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if k, err := base64.StdEncoding.DecodeString(key); err != nil || len(k) != 16 {
+		http.Error(w, "invalid websocket key", http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+
+	netConn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, "websocket is not supported", http.StatusInternalServerError)
+		return nil, errors.Wrap(err, "can not hijack connection")
+	}
+
+	err = netConn.SetDeadline(time.Time{})
+	if err != nil {
+		netConn.Close()
+		return nil, errors.Wrap(err, "can not reset connection deadline")
+	}
+
+	brw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n")
+
+	err = brw.Flush()
+	if err != nil {
+		netConn.Close()
+		return nil, errors.Wrap(err, "can not write handshake response")
+	}
+
+	return &Conn{
+		ReadLimit: defaultReadLimit,
+		conn:      netConn,
+		br:        brw.Reader,
+		bw:        brw.Writer,
+	}, nil
+}
+
+// Conn is a server side WebSocket connection.
+// ReadMessage must be called from a single goroutine, writes are safe for concurrent use.
+type Conn struct {
+	// ReadLimit is a maximum size of received message.
+	ReadLimit int64
+	// WriteTimeout limits every write to connection, zero means no limit.
+	WriteTimeout time.Duration
+	// OnPong is called by ReadMessage for every received pong.
+	OnPong func(payload []byte)
+
+	conn net.Conn
+	br   *bufio.Reader
+
+	mu     sync.Mutex
+	bw     *bufio.Writer
+	closed bool
+}
+
+// ReadMessage reads next text or binary message.
+// Pings are answered and pongs are reported to OnPong while reading.
+// When peer closes connection close frame is echoed and *CloseError is returned.
+func (c *Conn) ReadMessage() (int, []byte, error) {
+	var (
+		msg   []byte
+		msgOp = -1
+	)
+
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch op {
+		case OpPing:
+			err = c.WriteControl(OpPong, payload)
+			if err != nil {
+				return 0, nil, err
+			}
+			continue
+
+		case OpPong:
+			if c.OnPong != nil {
+				c.OnPong(payload)
+			}
+			continue
+
+		case OpClose:
+			// Close payload is either empty or starts with two bytes of status code
+			if len(payload) == 1 {
+				return 0, nil, c.fail(CloseProtocolError, "invalid close payload")
+			}
+
+			ce := &CloseError{Code: CloseNoStatus}
+			if len(payload) >= 2 {
+				ce.Code = int(binary.BigEndian.Uint16(payload))
+				ce.Text = string(payload[2:])
+			}
+
+			if ce.Code == CloseNoStatus {
+				c.writeClose(nil)
+			} else {
+				c.writeClose(payload[:2])
+			}
+			return 0, nil, ce
+
+		case OpContinuation:
+			if msgOp < 0 {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+
+		case OpText, OpBinary:
+			if msgOp >= 0 {
+				return 0, nil, c.fail(CloseProtocolError, "unfinished fragmented message")
+			}
+			msgOp = op
+
+		default:
+			return 0, nil, c.fail(CloseProtocolError, "unknown opcode")
+		}
+
+		if int64(len(msg)+len(payload)) > c.ReadLimit {
+			c.WriteClose(CloseTooLarge, "")
+			return 0, nil, ErrMessageTooLarge
+		}
+
+		msg = append(msg, payload...)
+		if !fin {
+			continue
+		}
+
+		if msgOp == OpText && !utf8.Valid(msg) {
+			return 0, nil, c.fail(CloseInvalidPayload, "invalid utf-8 text")
+		}
+
+		return msgOp, msg, nil
+	}
+}
+
+// WriteMessage sends single frame text or binary message.
+func (c *Conn) WriteMessage(op int, data []byte) error {
+	if op != OpText && op != OpBinary {
+		return errors.Errorf("invalid message opcode %d", op)
+	}
+
+	return c.write(op, data)
+}
+
+// WriteControl sends ping or pong frame.
+func (c *Conn) WriteControl(op int, data []byte) error {
+	if op != OpPing && op != OpPong {
+		return errors.Errorf("invalid control opcode %d", op)
+	}
+
+	if len(data) > maxControlPayload {
+		return errors.New("control frame payload is too large")
+	}
+
+	return c.write(op, data)
+}
+
+// WriteClose sends close frame with code and reason, no messages can be sent after it.
+func (c *Conn) WriteClose(code int, text string) error {
+	payload := make([]byte, 2, 2+len(text))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, text...)
+	if len(payload) > maxControlPayload {
+		payload = payload[:maxControlPayload]
+	}
+
+	return c.writeClose(payload)
+}
+
+// SetReadDeadline sets deadline of underlying connection reads.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// Close closes underlying connection without sending close frame.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Conn) readFrame() (bool, int, []byte, error) {
+	var h [2]byte
+	_, err := io.ReadFull(c.br, h[:])
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	fin := h[0]&0x80 != 0
+	op := int(h[0] & 0x0f)
+
+	if h[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "unsupported extension")
+	}
+
+	// Clients must mask every frame
+	if h[1]&0x80 == 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "unmasked frame")
+	}
+
+	n := uint64(h[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		_, err = io.ReadFull(c.br, ext[:])
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		_, err = io.ReadFull(c.br, ext[:])
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	if op >= OpClose && (n > maxControlPayload || !fin) {
+		return false, 0, nil, c.fail(CloseProtocolError, "invalid control frame")
+	}
+
+	if n > uint64(c.ReadLimit) {
+		c.WriteClose(CloseTooLarge, "")
+		return false, 0, nil, ErrMessageTooLarge
+	}
+
+	var mask [4]byte
+	_, err = io.ReadFull(c.br, mask[:])
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	payload := make([]byte, n)
+	_, err = io.ReadFull(c.br, payload)
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return fin, op, payload, nil
+}
+
+// fail closes connection because of protocol violation by peer.
+func (c *Conn) fail(code int, reason string) error {
+	c.WriteClose(code, reason)
+	return errors.New("websocket protocol error: " + reason)
+}
+
+func (c *Conn) writeClose(payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+
+	err := c.writeFrame(OpClose, payload)
+	c.closed = true
+	return err
+}
+
+func (c *Conn) write(op int, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrClosed
+	}
+
+	return c.writeFrame(op, data)
+}
+
+func (c *Conn) writeFrame(op int, data []byte) error {
+	if c.WriteTimeout > 0 {
+		err := c.conn.SetWriteDeadline(time.Now().Add(c.WriteTimeout))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Server frames are never fragmented nor masked
+	var h [10]byte
+	h[0] = 0x80 | byte(op)
+
+	n := len(data)
+	size := 2
+	switch {
+	case n <= maxControlPayload:
+		h[1] = byte(n)
+	case n <= 0xffff:
+		h[1] = 126
+		binary.BigEndian.PutUint16(h[2:], uint16(n))
+		size += 2
+	default:
+		h[1] = 127
+		binary.BigEndian.PutUint64(h[2:], uint64(n))
+		size += 8
+	}
+
+	c.bw.Write(h[:size])
+	c.bw.Write(data)
+	return c.bw.Flush()
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// originAllowed reports whether request without Origin header, e.g. from non-browser client,
+// or with Origin of request's host or one of allowed origins may be upgraded.
+func originAllowed(r *http.Request, allowed []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	for _, a := range allowed {
+		if strings.EqualFold(strings.TrimSuffix(a, "/"), origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasToken(header, token string) bool {
+	for _, t := range strings.Split(header, ",") {
+		if strings.EqualFold(strings.TrimSpace(t), token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,635 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testKey = "dGhlIHNhbXBsZSBub25jZQ=="
+
+// readResult is a result of ReadMessage on server side.
+type readResult struct {
+	op  int
+	msg []byte
+	err error
+}
+
+// newTestServer starts server that upgrades every request and passes connection to handle.
+func newTestServer(t *testing.T, handle func(c *Conn)) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+
+		handle(c)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// readServer starts server that reads single message and reports result to returned channel.
+func readServer(t *testing.T, setup func(c *Conn)) (*httptest.Server, chan readResult) {
+	t.Helper()
+
+	results := make(chan readResult, 1)
+	srv := newTestServer(t, func(c *Conn) {
+		if setup != nil {
+			setup(c)
+		}
+
+		op, msg, err := c.ReadMessage()
+		results <- readResult{op, msg, err}
+	})
+
+	return srv, results
+}
+
+// testClient is a raw client side of connection.
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+// dial connects to server and completes handshake.
+func dial(t *testing.T, srv *httptest.Server) *testClient {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", testKey)
+
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("unexpected handshake status %d", resp.StatusCode)
+	}
+
+	return &testClient{t: t, conn: conn, br: br}
+}
+
+// write sends masked frame.
+func (c *testClient) write(fin bool, op int, payload []byte) {
+	c.t.Helper()
+	c.writeRaw(fin, op, true, payload)
+}
+
+func (c *testClient) writeRaw(fin bool, op int, masked bool, payload []byte) {
+	c.t.Helper()
+
+	var b bytes.Buffer
+
+	h0 := byte(op)
+	if fin {
+		h0 |= 0x80
+	}
+	b.WriteByte(h0)
+
+	var h1 byte
+	if masked {
+		h1 = 0x80
+	}
+
+	n := len(payload)
+	switch {
+	case n < 126:
+		b.WriteByte(h1 | byte(n))
+	case n <= 0xffff:
+		b.WriteByte(h1 | 126)
+		binary.Write(&b, binary.BigEndian, uint16(n))
+	default:
+		b.WriteByte(h1 | 127)
+		binary.Write(&b, binary.BigEndian, uint64(n))
+	}
+
+	data := append([]byte(nil), payload...)
+	if masked {
+		mask := [4]byte{0x12, 0x34, 0x56, 0x78}
+		b.Write(mask[:])
+
+		for i := range data {
+			data[i] ^= mask[i%4]
+		}
+	}
+	b.Write(data)
+
+	if _, err := c.conn.Write(b.Bytes()); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// read receives frame sent by server.
+func (c *testClient) read() (bool, int, []byte) {
+	c.t.Helper()
+
+	var h [2]byte
+	if _, err := io.ReadFull(c.br, h[:]); err != nil {
+		c.t.Fatal(err)
+	}
+
+	if h[1]&0x80 != 0 {
+		c.t.Fatal("server frame is masked")
+	}
+
+	n := uint64(h[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(c.br, ext[:])
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(c.br, ext[:])
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		c.t.Fatal(err)
+	}
+
+	return h[0]&0x80 != 0, int(h[0] & 0x0f), payload
+}
+
+// readClose receives frame that must be close frame and returns its status code.
+func (c *testClient) readClose() (int, []byte) {
+	c.t.Helper()
+
+	_, op, payload := c.read()
+	if op != OpClose {
+		c.t.Fatalf("expected close frame, got opcode %d", op)
+	}
+
+	if len(payload) < 2 {
+		return CloseNoStatus, payload
+	}
+
+	return int(binary.BigEndian.Uint16(payload)), payload
+}
+
+func closePayload(code int, text string) []byte {
+	payload := make([]byte, 2, 2+len(text))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	return append(payload, text...)
+}
+
+func TestUpgrade(t *testing.T) {
+	srv := newTestServer(t, func(c *Conn) {})
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Connection", "keep-alive, Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", testKey)
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	req.Write(conn)
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("unexpected status %d", resp.StatusCode)
+	}
+
+	// Example from RFC 6455 section 1.3
+	if accept := resp.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected accept key %q", accept)
+	}
+
+	if !strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") || !strings.EqualFold(resp.Header.Get("Connection"), "upgrade") {
+		t.Fatalf("unexpected upgrade headers %v", resp.Header)
+	}
+}
+
+func TestUpgradeBadHandshake(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		header map[string]string
+		status int
+		// err is expected error, ErrBadHandshake by default
+		err error
+	}{
+		{
+			name:   "post",
+			method: http.MethodPost,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "no upgrade",
+			header: map[string]string{"Upgrade": ""},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "unsupported version",
+			header: map[string]string{"Sec-WebSocket-Version": "8"},
+			status: http.StatusUpgradeRequired,
+		},
+		{
+			name:   "no key",
+			header: map[string]string{"Sec-WebSocket-Key": ""},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "short key",
+			header: map[string]string{"Sec-WebSocket-Key": "c2hvcnQ="},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "cross-site origin",
+			header: map[string]string{"Origin": "https://evil.example.com"},
+			status: http.StatusForbidden,
+			err:    ErrBadOrigin,
+		},
+		{
+			name:   "malformed origin",
+			header: map[string]string{"Origin": "null"},
+			status: http.StatusForbidden,
+			err:    ErrBadOrigin,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := make(chan error, 1)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, err := Upgrade(w, r, nil)
+				errs <- err
+			}))
+			defer srv.Close()
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			req, _ := http.NewRequest(method, srv.URL, nil)
+			req.Header.Set("Connection", "Upgrade")
+			req.Header.Set("Upgrade", "websocket")
+			req.Header.Set("Sec-WebSocket-Version", "13")
+			req.Header.Set("Sec-WebSocket-Key", testKey)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+
+			expected := tt.err
+			if expected == nil {
+				expected = ErrBadHandshake
+			}
+
+			if err := <-errs; err != expected {
+				t.Fatalf("expected %v, got %v", expected, err)
+			}
+		})
+	}
+}
+
+func TestUpgradeOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		origin  func(srv *httptest.Server) string
+		allowed []string
+		status  int
+	}{
+		{
+			name:   "no origin",
+			origin: func(*httptest.Server) string { return "" },
+			status: http.StatusSwitchingProtocols,
+		},
+		{
+			name:   "same origin",
+			origin: func(srv *httptest.Server) string { return srv.URL },
+			status: http.StatusSwitchingProtocols,
+		},
+		{
+			name:    "allowed origin",
+			origin:  func(*httptest.Server) string { return "https://app.example.com" },
+			allowed: []string{"https://app.example.com/"},
+			status:  http.StatusSwitchingProtocols,
+		},
+		{
+			name:    "other origin",
+			origin:  func(*httptest.Server) string { return "https://evil.example.com" },
+			allowed: []string{"https://app.example.com"},
+			status:  http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				c, err := Upgrade(w, r, tt.allowed)
+				if err == nil {
+					c.Close()
+				}
+			}))
+			defer srv.Close()
+
+			conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+			req.Header.Set("Connection", "Upgrade")
+			req.Header.Set("Upgrade", "websocket")
+			req.Header.Set("Sec-WebSocket-Version", "13")
+			req.Header.Set("Sec-WebSocket-Key", testKey)
+			if origin := tt.origin(srv); origin != "" {
+				req.Header.Set("Origin", origin)
+			}
+
+			req.Write(conn)
+
+			resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestReadMessageFragmented(t *testing.T) {
+	srv := newTestServer(t, func(c *Conn) {
+		op, msg, err := c.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		c.WriteMessage(op, msg)
+	})
+
+	c := dial(t, srv)
+
+	long := strings.Repeat("x", 300)
+
+	c.write(false, OpText, []byte("hello, "))
+	c.write(true, OpPing, []byte("ping"))
+	c.write(false, OpContinuation, []byte(long))
+	c.write(true, OpContinuation, []byte(" world"))
+
+	fin, op, payload := c.read()
+	if !fin || op != OpPong || string(payload) != "ping" {
+		t.Fatalf("expected pong answering ping in the middle of message, got fin %v opcode %d %q", fin, op, payload)
+	}
+
+	fin, op, payload = c.read()
+	if !fin || op != OpText || string(payload) != "hello, "+long+" world" {
+		t.Fatalf("expected reassembled message echoed, got fin %v opcode %d %q", fin, op, payload)
+	}
+}
+
+func TestReadMessagePong(t *testing.T) {
+	pongs := make(chan string, 1)
+	srv, results := readServer(t, func(c *Conn) {
+		c.OnPong = func(payload []byte) {
+			pongs <- string(payload)
+		}
+	})
+
+	c := dial(t, srv)
+	c.write(true, OpPong, []byte("beat"))
+	c.write(true, OpBinary, []byte{1, 2, 3})
+
+	if p := <-pongs; p != "beat" {
+		t.Fatalf("unexpected pong payload %q", p)
+	}
+
+	r := <-results
+	if r.err != nil || r.op != OpBinary || !bytes.Equal(r.msg, []byte{1, 2, 3}) {
+		t.Fatalf("unexpected message opcode %d %v %v", r.op, r.msg, r.err)
+	}
+}
+
+func TestReadMessageClose(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		// code is a code of returned CloseError
+		code int
+		text string
+		// echo is a code of close frame echoed by server
+		echo int
+	}{
+		{
+			name:    "with status",
+			payload: closePayload(CloseNormal, "bye"),
+			code:    CloseNormal,
+			text:    "bye",
+			echo:    CloseNormal,
+		},
+		{
+			name:    "going away",
+			payload: closePayload(CloseGoingAway, ""),
+			code:    CloseGoingAway,
+			echo:    CloseGoingAway,
+		},
+		{
+			name:    "no status",
+			payload: nil,
+			code:    CloseNoStatus,
+			echo:    CloseNoStatus,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, results := readServer(t, nil)
+
+			c := dial(t, srv)
+			c.write(true, OpClose, tt.payload)
+
+			code, payload := c.readClose()
+			if code != tt.echo {
+				t.Fatalf("expected echoed code %d, got %d", tt.echo, code)
+			}
+
+			if len(payload) > 2 {
+				t.Fatalf("expected echo without reason, got %q", payload)
+			}
+
+			r := <-results
+			ce, ok := r.err.(*CloseError)
+			if !ok {
+				t.Fatalf("expected close error, got %v", r.err)
+			}
+
+			if ce.Code != tt.code || ce.Text != tt.text {
+				t.Fatalf("expected close %d %q, got %d %q", tt.code, tt.text, ce.Code, ce.Text)
+			}
+		})
+	}
+}
+
+func TestReadMessageProtocolError(t *testing.T) {
+	type frame struct {
+		fin     bool
+		op      int
+		payload []byte
+	}
+
+	tests := []struct {
+		name   string
+		frames []frame
+		// unmasked sends the last frame unmasked
+		unmasked bool
+		code     int
+	}{
+		{
+			name:   "one byte close payload",
+			frames: []frame{{true, OpClose, []byte{0x03}}},
+			code:   CloseProtocolError,
+		},
+		{
+			name:     "unmasked",
+			frames:   []frame{{true, OpText, []byte("hi")}},
+			unmasked: true,
+			code:     CloseProtocolError,
+		},
+		{
+			name:   "unexpected continuation",
+			frames: []frame{{true, OpContinuation, []byte("hi")}},
+			code:   CloseProtocolError,
+		},
+		{
+			name: "unfinished fragmented message",
+			frames: []frame{
+				{false, OpText, []byte("hi")},
+				{true, OpText, []byte("there")},
+			},
+			code: CloseProtocolError,
+		},
+		{
+			name:   "fragmented control frame",
+			frames: []frame{{false, OpPing, []byte("hi")}},
+			code:   CloseProtocolError,
+		},
+		{
+			name:   "control frame too large",
+			frames: []frame{{true, OpPing, bytes.Repeat([]byte("x"), maxControlPayload+1)}},
+			code:   CloseProtocolError,
+		},
+		{
+			name:   "unknown opcode",
+			frames: []frame{{true, 0x3, []byte("hi")}},
+			code:   CloseProtocolError,
+		},
+		{
+			name:   "invalid utf-8",
+			frames: []frame{{true, OpText, []byte{0xff, 0xfe}}},
+			code:   CloseInvalidPayload,
+		},
+		{
+			name: "message too large",
+			frames: []frame{
+				{false, OpBinary, bytes.Repeat([]byte("x"), 10)},
+				{true, OpContinuation, bytes.Repeat([]byte("x"), 10)},
+			},
+			code: CloseTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, results := readServer(t, func(c *Conn) {
+				c.ReadLimit = 16
+			})
+
+			c := dial(t, srv)
+			for i, f := range tt.frames {
+				c.writeRaw(f.fin, f.op, !tt.unmasked || i < len(tt.frames)-1, f.payload)
+			}
+
+			code, _ := c.readClose()
+			if code != tt.code {
+				t.Fatalf("expected close code %d, got %d", tt.code, code)
+			}
+
+			r := <-results
+			if r.err == nil {
+				t.Fatal("expected error")
+			}
+
+			if _, ok := r.err.(*CloseError); ok {
+				t.Fatalf("expected protocol error, got %v", r.err)
+			}
+		})
+	}
+}
+
+func TestWriteAfterClose(t *testing.T) {
+	errs := make(chan error, 1)
+	srv := newTestServer(t, func(c *Conn) {
+		c.WriteClose(CloseGoingAway, "shutdown")
+		errs <- c.WriteMessage(OpText, []byte("late"))
+	})
+
+	c := dial(t, srv)
+
+	code, payload := c.readClose()
+	if code != CloseGoingAway || string(payload[2:]) != "shutdown" {
+		t.Fatalf("unexpected close frame %d %q", code, payload)
+	}
+
+	if err := <-errs; err != ErrClosed {
+		t.Fatalf("expected %v, got %v", ErrClosed, err)
+	}
+}
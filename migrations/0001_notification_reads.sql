@@ -0,0 +1,8 @@
+-- Per user read state of notifications.
+-- Global notifications (user_id is null) are shared, so read marks can't be stored in notifications table.
+CREATE TABLE IF NOT EXISTS app.notification_reads (
+    user_id         bigint      NOT NULL,
+    notification_id integer     NOT NULL REFERENCES app.notifications (id) ON DELETE CASCADE,
+    read_at         timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, notification_id)
+);
@@ -0,0 +1,4 @@
+-- Creation time is used for keyset pagination of notification listings.
+ALTER TABLE app.notifications ADD COLUMN IF NOT EXISTS created_at timestamptz NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS notifications_created_at_id_idx ON app.notifications (created_at DESC, id DESC);
@@ -0,0 +1,3 @@
+-- Revoked notifications are kept for audit, version is used for optimistic concurrency control.
+ALTER TABLE app.notifications ADD COLUMN IF NOT EXISTS revoked_at timestamptz;
+ALTER TABLE app.notifications ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 1;
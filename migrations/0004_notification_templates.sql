@@ -0,0 +1,8 @@
+-- Notification templates keyed by notification type.
+CREATE TABLE IF NOT EXISTS app.notification_templates (
+    type       text        PRIMARY KEY,
+    title      text        NOT NULL,
+    body       text        NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
@@ -0,0 +1,8 @@
+-- Per locale variants of notification's title and body.
+CREATE TABLE IF NOT EXISTS app.notification_translations (
+    notification_id integer NOT NULL REFERENCES app.notifications (id) ON DELETE CASCADE,
+    locale          text    NOT NULL,
+    title           text    NOT NULL,
+    body            text    NOT NULL,
+    PRIMARY KEY (notification_id, locale)
+);
@@ -0,0 +1,6 @@
+-- Priority rank: 0 - low, 1 - normal, 2 - high, 3 - critical.
+ALTER TABLE app.notifications ADD COLUMN IF NOT EXISTS priority smallint NOT NULL DEFAULT 1;
+
+DROP INDEX IF EXISTS app.notifications_created_at_id_idx;
+CREATE INDEX IF NOT EXISTS notifications_priority_created_at_id_idx
+    ON app.notifications (priority DESC, created_at DESC, id DESC);
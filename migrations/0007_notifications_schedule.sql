@@ -0,0 +1,8 @@
+-- Scheduled publishing: notifications with published_at null are published by dispatcher at publish_at.
+ALTER TABLE app.notifications ADD COLUMN IF NOT EXISTS publish_at timestamptz;
+ALTER TABLE app.notifications ADD COLUMN IF NOT EXISTS published_at timestamptz;
+
+UPDATE app.notifications SET published_at = created_at WHERE published_at IS NULL AND publish_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS notifications_due_idx
+    ON app.notifications (publish_at, id) WHERE published_at IS NULL AND revoked_at IS NULL;
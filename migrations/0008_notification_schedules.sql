@@ -0,0 +1,19 @@
+-- Recurring notification schedules defined by cron expressions.
+CREATE TABLE IF NOT EXISTS app.notification_schedules (
+    id          serial      PRIMARY KEY,
+    cron        text        NOT NULL,
+    timezone    text        NOT NULL,
+    catch_up    text        NOT NULL,
+    user_id     bigint,
+    type        text        NOT NULL,
+    title       text        NOT NULL,
+    body        text        NOT NULL,
+    priority    smallint    NOT NULL DEFAULT 1,
+    paused      boolean     NOT NULL DEFAULT false,
+    next_run_at timestamptz,
+    last_run_at timestamptz,
+    created_at  timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS notification_schedules_due_idx
+    ON app.notification_schedules (next_run_at, id) WHERE NOT paused;
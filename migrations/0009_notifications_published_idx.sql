@@ -0,0 +1,3 @@
+-- Resuming notification streams reads notifications ordered by publishing time.
+CREATE INDEX IF NOT EXISTS notifications_published_at_id_idx
+    ON app.notifications (published_at, id) WHERE published_at IS NOT NULL;
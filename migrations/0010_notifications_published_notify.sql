@@ -0,0 +1,17 @@
+-- Every service instance listens to notifications_published channel to deliver notifications
+-- published by other instances to its live streams. Payload is the notification id,
+-- notifications are delivered on commit only.
+CREATE OR REPLACE FUNCTION app.notify_notification_published() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('notifications_published', NEW.id::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS notifications_published_notify ON app.notifications;
+
+CREATE TRIGGER notifications_published_notify
+    AFTER INSERT OR UPDATE OF published_at ON app.notifications
+    FOR EACH ROW
+    WHEN (NEW.published_at IS NOT NULL)
+    EXECUTE PROCEDURE app.notify_notification_published();
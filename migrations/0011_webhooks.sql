@@ -0,0 +1,40 @@
+-- Webhook subscriptions of external systems and history of event deliveries to them.
+CREATE TABLE IF NOT EXISTS app.webhooks (
+    id         serial      PRIMARY KEY,
+    url        text        NOT NULL,
+    events     jsonb       NOT NULL DEFAULT '[]',
+    types      jsonb       NOT NULL DEFAULT '[]',
+    secret     text        NOT NULL,
+    active     boolean     NOT NULL DEFAULT true,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS app.webhook_deliveries (
+    id              bigserial   PRIMARY KEY,
+    webhook_id      integer     NOT NULL REFERENCES app.webhooks (id) ON DELETE CASCADE,
+    event           text        NOT NULL,
+    payload         jsonb       NOT NULL,
+    status          text        NOT NULL DEFAULT 'pending',
+    attempts        integer     NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz,
+    created_at      timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_idx
+    ON app.webhook_deliveries (webhook_id, id);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx
+    ON app.webhook_deliveries (next_attempt_at, id) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS app.webhook_attempts (
+    id          bigserial   PRIMARY KEY,
+    delivery_id bigint      NOT NULL REFERENCES app.webhook_deliveries (id) ON DELETE CASCADE,
+    attempt     integer     NOT NULL,
+    status_code integer     NOT NULL DEFAULT 0,
+    error       text        NOT NULL DEFAULT '',
+    duration_ms bigint      NOT NULL,
+    at          timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS webhook_attempts_delivery_idx
+    ON app.webhook_attempts (delivery_id, attempt);
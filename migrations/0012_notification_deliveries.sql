@@ -0,0 +1,17 @@
+-- Deliveries of notifications to users through external channels (email, push...).
+CREATE TABLE IF NOT EXISTS app.notification_deliveries (
+    id              bigserial   PRIMARY KEY,
+    notification_id integer     NOT NULL REFERENCES app.notifications (id) ON DELETE CASCADE,
+    user_id         bigint      NOT NULL,
+    channel         text        NOT NULL,
+    status          text        NOT NULL DEFAULT 'pending',
+    attempts        integer     NOT NULL DEFAULT 0,
+    error           text        NOT NULL DEFAULT '',
+    next_attempt_at timestamptz,
+    sent_at         timestamptz,
+    created_at      timestamptz NOT NULL DEFAULT now(),
+    UNIQUE (notification_id, user_id, channel)
+);
+
+CREATE INDEX IF NOT EXISTS notification_deliveries_due_idx
+    ON app.notification_deliveries (next_attempt_at, id) WHERE status = 'pending';
@@ -0,0 +1,12 @@
+-- Devices registered by users to receive push notifications, token belongs to the latest user registered it.
+CREATE TABLE IF NOT EXISTS app.devices (
+    id         serial      PRIMARY KEY,
+    user_id    bigint      NOT NULL,
+    platform   text        NOT NULL,
+    token      text        NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    UNIQUE (platform, token)
+);
+
+CREATE INDEX IF NOT EXISTS devices_user_idx ON app.devices (user_id);
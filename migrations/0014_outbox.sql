@@ -0,0 +1,18 @@
+-- Events written in the same transaction as notification changes, relay publishes them at least once.
+CREATE TABLE IF NOT EXISTS app.outbox (
+    id              bigserial   PRIMARY KEY,
+    event           text        NOT NULL,
+    notification_id integer     NOT NULL REFERENCES app.notifications (id) ON DELETE CASCADE,
+    status          text        NOT NULL DEFAULT 'pending',
+    attempts        integer     NOT NULL DEFAULT 0,
+    error           text        NOT NULL DEFAULT '',
+    next_attempt_at timestamptz DEFAULT now(),
+    created_at      timestamptz NOT NULL DEFAULT now(),
+    published_at    timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS outbox_due_idx
+    ON app.outbox (next_attempt_at, id) WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS outbox_status_idx
+    ON app.outbox (status, id);
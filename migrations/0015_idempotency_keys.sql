@@ -0,0 +1,16 @@
+-- Idempotency keys of requests along with snapshots of their responses.
+CREATE TABLE IF NOT EXISTS app.idempotency_keys (
+    user_id      bigint      NOT NULL,
+    key          text        NOT NULL,
+    request_hash text        NOT NULL,
+    status       text        NOT NULL DEFAULT 'in_flight',
+    status_code  integer     NOT NULL DEFAULT 0,
+    content_type text        NOT NULL DEFAULT '',
+    response     bytea,
+    created_at   timestamptz NOT NULL DEFAULT now(),
+    expires_at   timestamptz NOT NULL,
+    PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_idx
+    ON app.idempotency_keys (expires_at);
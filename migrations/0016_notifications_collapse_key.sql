@@ -0,0 +1,15 @@
+-- Collapse keys: notification with the same key and recipient as active notification is merged into it.
+ALTER TABLE app.notifications ADD COLUMN IF NOT EXISTS collapse_key text;
+ALTER TABLE app.notifications ADD COLUMN IF NOT EXISTS collapse_count integer NOT NULL DEFAULT 1;
+ALTER TABLE app.notifications ADD COLUMN IF NOT EXISTS last_seen_at timestamptz;
+
+UPDATE app.notifications SET last_seen_at = created_at WHERE last_seen_at IS NULL;
+
+ALTER TABLE app.notifications ALTER COLUMN last_seen_at SET DEFAULT now();
+ALTER TABLE app.notifications ALTER COLUMN last_seen_at SET NOT NULL;
+
+-- Broadcast notifications (user_id is null) share keys as well, so user_id is coalesced to -1.
+-- Revoked notifications release their keys.
+CREATE UNIQUE INDEX IF NOT EXISTS notifications_collapse_key_idx
+    ON app.notifications ((COALESCE(user_id, -1)), collapse_key)
+    WHERE collapse_key IS NOT NULL AND revoked_at IS NULL;
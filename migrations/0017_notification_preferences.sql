@@ -0,0 +1,9 @@
+-- Per user preferences of notification types by channel, missing preference means type is enabled.
+CREATE TABLE IF NOT EXISTS app.notification_preferences (
+    user_id    bigint      NOT NULL,
+    type       text        NOT NULL,
+    channel    text        NOT NULL,
+    enabled    boolean     NOT NULL,
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, type, channel)
+);
@@ -0,0 +1,8 @@
+-- Daily windows in user's timezone when deliveries through channels are deferred.
+CREATE TABLE IF NOT EXISTS app.quiet_hours (
+    user_id    bigint      PRIMARY KEY,
+    timezone   text        NOT NULL,
+    start_time text        NOT NULL,
+    end_time   text        NOT NULL,
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
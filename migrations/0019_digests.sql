@@ -0,0 +1,19 @@
+-- Users' digest settings, published notifications up to max_priority are collected and sent as summary at next_at.
+-- Priority rank: 0 - low, 1 - normal, 2 - high, 3 - critical.
+CREATE TABLE IF NOT EXISTS app.digests (
+    user_id      bigint      PRIMARY KEY,
+    frequency    text        NOT NULL,
+    channel      text        NOT NULL,
+    max_priority smallint    NOT NULL,
+    timezone     text        NOT NULL,
+    next_at      timestamptz NOT NULL,
+    updated_at   timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS digests_next_at_idx ON app.digests (next_at);
+
+-- Deliveries collected to digest have status 'collected' until digest is sent.
+ALTER TABLE app.notification_deliveries ADD COLUMN IF NOT EXISTS digested_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS notification_deliveries_collected_idx
+    ON app.notification_deliveries (user_id, channel, id) WHERE status = 'collected';
@@ -0,0 +1,26 @@
+-- Full-text search over title and body. Language is a text search configuration notification is indexed with,
+-- title is weighted higher than body.
+ALTER TABLE app.notifications ADD COLUMN IF NOT EXISTS language regconfig NOT NULL DEFAULT 'simple';
+ALTER TABLE app.notifications ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+CREATE OR REPLACE FUNCTION app.notifications_search_vector() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector(NEW.language, NEW.title), 'A') ||
+        setweight(to_tsvector(NEW.language, NEW.body), 'B');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS notifications_search_vector ON app.notifications;
+
+CREATE TRIGGER notifications_search_vector
+    BEFORE INSERT OR UPDATE OF title, body, language ON app.notifications
+    FOR EACH ROW
+    EXECUTE PROCEDURE app.notifications_search_vector();
+
+UPDATE app.notifications
+SET search_vector = setweight(to_tsvector(language, title), 'A') || setweight(to_tsvector(language, body), 'B')
+WHERE search_vector IS NULL;
+
+CREATE INDEX IF NOT EXISTS notifications_search_idx ON app.notifications USING gin (search_vector);
@@ -0,0 +1,3 @@
+-- Reason schedule was paused by failed run.
+ALTER TABLE app.notification_schedules
+    ADD COLUMN IF NOT EXISTS last_error text NOT NULL DEFAULT '';
@@ -0,0 +1,37 @@
+-- Notification streams are resumed from sequence number assigned by database on publishing.
+-- Publishing time can't be used as it comes from clocks of service instances as well as database.
+CREATE SEQUENCE IF NOT EXISTS app.notifications_publish_seq;
+
+ALTER TABLE app.notifications ADD COLUMN IF NOT EXISTS publish_seq bigint;
+
+UPDATE app.notifications n
+SET publish_seq = p.seq
+FROM (
+    SELECT id, nextval('app.notifications_publish_seq') AS seq
+    FROM (
+        SELECT id FROM app.notifications
+        WHERE published_at IS NOT NULL AND publish_seq IS NULL
+        ORDER BY published_at, id
+    ) o
+) p
+WHERE n.id = p.id;
+
+CREATE OR REPLACE FUNCTION app.notifications_publish_seq() RETURNS trigger AS $$
+BEGIN
+    NEW.publish_seq := nextval('app.notifications_publish_seq');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS notifications_publish_seq ON app.notifications;
+
+CREATE TRIGGER notifications_publish_seq
+    BEFORE INSERT OR UPDATE OF published_at ON app.notifications
+    FOR EACH ROW
+    WHEN (NEW.published_at IS NOT NULL AND NEW.publish_seq IS NULL)
+    EXECUTE PROCEDURE app.notifications_publish_seq();
+
+CREATE UNIQUE INDEX IF NOT EXISTS notifications_publish_seq_idx
+    ON app.notifications (publish_seq) WHERE publish_seq IS NOT NULL;
+
+DROP INDEX IF EXISTS app.notifications_published_at_id_idx;